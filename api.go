@@ -1,38 +1,26 @@
 package gslb
 
 import (
-	"bytes"
 	"encoding/json"
+	"net"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/miekg/dns"
 )
 
 const statusHealthy = "healthy"
 const statusUnhealthy = "unhealthy"
-
-// checkBasicAuth checks HTTP Basic Auth if configured, returns true if authorized, false otherwise.
-func (g *GSLB) checkBasicAuth(w http.ResponseWriter, r *http.Request) bool {
-	if g.APIBasicUser != "" && g.APIBasicPass != "" {
-		user, pass, ok := r.BasicAuth()
-		if !ok || user != g.APIBasicUser || pass != g.APIBasicPass {
-			w.Header().Set("WWW-Authenticate", `Basic realm="GSLB API"`)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
-			return false
-		}
-	}
-	return true
-}
+const statusDisabled = "disabled"
+const statusMaintenance = "maintenance"
 
 // handleBulkSetBackendEnable returns a handler that enables or disables backends in bulk.
+// Changes are runtime overrides, the zone files are left untouched.
 func (g *GSLB) handleBulkSetBackendEnable(enable bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !g.checkBasicAuth(w, r) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
 			return
 		}
 		if r.Method != http.MethodPost {
@@ -41,45 +29,271 @@ func (g *GSLB) handleBulkSetBackendEnable(enable bool) http.HandlerFunc {
 			return
 		}
 		var req struct {
+			Record        string   `json:"record"`
 			Location      string   `json:"location"`
 			AddressPrefix string   `json:"address_prefix"`
 			Tags          []string `json:"tags"`
+			Reason        string   `json:"reason"`
+			ExpiresIn     string   `json:"expires_in"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
 			return
 		}
-		if req.Location == "" && req.AddressPrefix == "" && len(req.Tags) == 0 {
+		if req.Record == "" && req.Location == "" && req.AddressPrefix == "" && len(req.Tags) == 0 {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "location, address_prefix, or tags required"})
+			json.NewEncoder(w).Encode(map[string]string{"error": "record, location, address_prefix, or tags required"})
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresIn != "" {
+			d, err := time.ParseDuration(req.ExpiresIn)
+			if err != nil || d <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "expires_in must be a positive duration"})
+				return
+			}
+			t := time.Now().Add(d)
+			expiresAt = &t
+		}
+		if req.Record != "" && !strings.HasSuffix(req.Record, ".") {
+			req.Record += "."
+		}
+
+		// A record alone selects all its backends
+		selectorSet := req.Location != "" || req.AddressPrefix != "" || len(req.Tags) > 0
+
+		modified := []map[string]string{}
+		var changes []AuditChange
+		g.Mutex.RLock()
+		for zone, records := range g.Records {
+			for fqdn, record := range records {
+				if (req.Record != "" && fqdn != req.Record) || !cred.allowsRecord(zone, record.Owner) {
+					continue
+				}
+				for _, backend := range record.Backends {
+					if selectorSet && !backendMatches(backend, req.Location, req.AddressPrefix, req.Tags) {
+						continue
+					}
+					before := backend.IsEnabled()
+					g.setOverride(fqdn, backend, enable, req.Reason, expiresAt)
+					changes = append(changes, enableChange(fqdn, backend, before))
+					modified = append(modified, map[string]string{
+						"record":  fqdn,
+						"address": backend.GetAddress(),
+					})
+				}
+			}
+		}
+		g.Mutex.RUnlock()
+
+		err := g.saveOverrides()
+		action := AuditBackendsDisable
+		if enable {
+			action = AuditBackendsEnable
+		}
+		g.auditRequest(r, cred, AuditEntry{Action: action, Request: req, Changes: changes}, err)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"backends": modified,
+		})
+	}
+}
+
+// handleOverrides returns a handler to list (GET) or clear (DELETE ?record=&address=) backend overrides.
+func (g *GSLB) handleOverrides() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
 			return
 		}
-		var allModified []map[string]string
-		for _, yamlFile := range g.Zones {
-			modified, err := bulkSetBackendEnable(yamlFile, req.Location, req.AddressPrefix, req.Tags, enable)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]interface{}{"overrides": g.allowedOverrides(cred)})
+		case http.MethodDelete:
+			record := r.URL.Query().Get("record")
+			address := r.URL.Query().Get("address")
+			if record == "" || address == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "record and address required"})
+				return
+			}
+			if !strings.HasSuffix(record, ".") {
+				record += "."
+			}
+			g.Mutex.RLock()
+			if !g.recordAllowed(cred, record) {
+				g.Mutex.RUnlock()
+				g.denyScope(w, r, cred)
+				return
+			}
+			backend := g.findBackend(record, address)
+			before := backend != nil && backend.IsEnabled()
+			found := g.clearOverride(record, address)
+			var changes []AuditChange
+			if found && backend != nil {
+				changes = append(changes, enableChange(record, backend, before))
+			}
+			g.Mutex.RUnlock()
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Override not found"})
+				return
+			}
+			err := g.saveOverrides()
+			g.auditRequest(r, cred, AuditEntry{Action: AuditOverrideClear, Request: map[string]string{"record": record, "address": address}, Changes: changes}, err)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 				return
 			}
-			allModified = append(allModified, modified...)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET and DELETE are supported."})
 		}
-		resp := map[string]interface{}{
-			"success":  true,
-			"backends": allModified,
+	}
+}
+
+// handleMaintenance returns a handler to schedule (POST), list (GET) or delete (DELETE ?id=)
+// maintenance windows created through the API.
+func (g *GSLB) handleMaintenance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
+			return
 		}
-		if resp["backends"] == nil {
-			resp["backends"] = []map[string]string{}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]interface{}{"maintenance": g.allowedMaintenance(cred)})
+		case http.MethodPost:
+			var req struct {
+				Record        string   `json:"record"`
+				Location      string   `json:"location"`
+				AddressPrefix string   `json:"address_prefix"`
+				Tags          []string `json:"tags"`
+				Start         string   `json:"start"`
+				End           string   `json:"end"`
+				Recurrence    string   `json:"recurrence"`
+				Reason        string   `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+				return
+			}
+			if req.Record == "" && req.Location == "" && req.AddressPrefix == "" && len(req.Tags) == 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "record, location, address_prefix, or tags required"})
+				return
+			}
+			start, errStart := time.Parse(time.RFC3339, req.Start)
+			end, errEnd := time.Parse(time.RFC3339, req.End)
+			if errStart != nil || errEnd != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "start and end must be RFC3339 times"})
+				return
+			}
+			window := MaintenanceWindow{ID: newMaintenanceID(), Start: start, End: end, Recurrence: req.Recurrence, Reason: req.Reason}
+			if err := window.validate(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			if req.Record != "" && !strings.HasSuffix(req.Record, ".") {
+				req.Record += "."
+			}
+			// A record alone selects all its backends
+			selectorSet := req.Location != "" || req.AddressPrefix != "" || len(req.Tags) > 0
+
+			scheduled := []map[string]string{}
+			var changes []AuditChange
+			g.Mutex.RLock()
+			for zone, records := range g.Records {
+				for fqdn, record := range records {
+					if (req.Record != "" && fqdn != req.Record) || !cred.allowsRecord(zone, record.Owner) {
+						continue
+					}
+					for _, backend := range record.Backends {
+						if selectorSet && !backendMatches(backend, req.Location, req.AddressPrefix, req.Tags) {
+							continue
+						}
+						g.addMaintenance(fqdn, backend, window)
+						changes = append(changes, AuditChange{Record: fqdn, Address: backend.GetAddress()})
+						scheduled = append(scheduled, map[string]string{
+							"record":  fqdn,
+							"address": backend.GetAddress(),
+						})
+					}
+				}
+			}
+			g.Mutex.RUnlock()
+
+			err := g.saveOverrides()
+			g.auditRequest(r, cred, AuditEntry{Action: AuditMaintenanceAdd, Request: req, Changes: changes}, err)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":  true,
+				"id":       window.ID,
+				"backends": scheduled,
+			})
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "id required"})
+				return
+			}
+			if !g.maintenanceIDAllowed(cred, id) {
+				g.denyScope(w, r, cred)
+				return
+			}
+			g.Mutex.RLock()
+			var changes []AuditChange
+			for _, m := range g.listMaintenance() {
+				if m.ID == id {
+					changes = append(changes, AuditChange{Record: m.Record, Address: m.Address})
+				}
+			}
+			found := g.removeMaintenance(id)
+			g.Mutex.RUnlock()
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Maintenance window not found"})
+				return
+			}
+			err := g.saveOverrides()
+			g.auditRequest(r, cred, AuditEntry{Action: AuditMaintenanceDelete, Request: map[string]string{"id": id}, Changes: changes}, err)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET, POST and DELETE are supported."})
 		}
-		json.NewEncoder(w).Encode(resp)
 	}
 }
 
 // handleOverview returns a simplified overview of all records and their backends.
 func (g *GSLB) handleOverview() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !g.checkBasicAuth(w, r) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
 			return
 		}
 		if r.Method != http.MethodGet {
@@ -105,194 +319,296 @@ func (g *GSLB) handleOverview() http.HandlerFunc {
 
 		if zone != "" {
 			recs, ok := g.Records[zone]
+			if ok && !cred.allowsZone(zone) {
+				g.denyScope(w, r, cred)
+				return
+			}
 			if !ok {
 				w.WriteHeader(http.StatusNotFound)
 				json.NewEncoder(w).Encode(map[string]string{"error": "Zone not found"})
 				return
 			}
-			var records []map[string]interface{}
-			for _, rec := range recs {
-				rec.mutex.RLock()
-				atLeastOneBackendHealthy := false
-				var backends []map[string]interface{}
-				for _, be := range rec.Backends {
-					b := be.(*Backend)
-					b.mutex.RLock()
-					aliveStr := statusUnhealthy
-					if b.Alive && b.Enable {
-						aliveStr = statusHealthy
-						atLeastOneBackendHealthy = true
-					}
-					beMap := map[string]interface{}{
-						"address":          b.Address,
-						"alive":            aliveStr,
-						"last_healthcheck": b.LastHealthcheck.Format(time.RFC3339),
-					}
-					b.mutex.RUnlock()
-					backends = append(backends, beMap)
-				}
-				recMap := map[string]interface{}{
-					"record": rec.Fqdn,
-					"status": func() string {
-						if atLeastOneBackendHealthy {
-							return statusHealthy
-						}
-						return statusUnhealthy
-					}(),
-					"backends": backends,
-				}
-				records = append(records, recMap)
-				rec.mutex.RUnlock()
-			}
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(records)
+			json.NewEncoder(w).Encode(overviewRecords(cred, zone, recs))
 			return
 		}
 
 		// Default: return all zones
 		resp := make(map[string][]map[string]interface{})
 		for zone, recs := range g.Records {
-			var records []map[string]interface{}
-			for _, rec := range recs {
-				rec.mutex.RLock()
-				atLeastOneBackendHealthy := false
-				var backends []map[string]interface{}
-				for _, be := range rec.Backends {
-					b := be.(*Backend)
-					b.mutex.RLock()
-					aliveStr := statusUnhealthy
-					if b.Alive && b.Enable {
-						aliveStr = statusHealthy
-						atLeastOneBackendHealthy = true
-					}
-					beMap := map[string]interface{}{
-						"address":          b.Address,
-						"alive":            aliveStr,
-						"last_healthcheck": b.LastHealthcheck.Format(time.RFC3339),
-					}
-					b.mutex.RUnlock()
-					backends = append(backends, beMap)
-				}
-				recMap := map[string]interface{}{
-					"record": rec.Fqdn,
-					"status": func() string {
-						if atLeastOneBackendHealthy {
-							return statusHealthy
-						}
-						return statusUnhealthy
-					}(),
-					"backends": backends,
-				}
-				records = append(records, recMap)
-				rec.mutex.RUnlock()
+			if cred.allowsZone(zone) {
+				resp[zone] = overviewRecords(cred, zone, recs)
 			}
-			resp[zone] = records
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
 	}
 }
 
-// RegisterAPIHandlers registers all API endpoints to the provided mux.
-func (g *GSLB) RegisterAPIHandlers(mux *http.ServeMux) {
-	// Handler for /api/overview
-	mux.HandleFunc("/api/overview", g.handleOverview())
-	mux.HandleFunc("/api/overview/", g.handleOverview())
-
-	// Handler for bulk disable (POST /api/backends/disable)
-	mux.HandleFunc("/api/backends/disable", g.handleBulkSetBackendEnable(false))
-	// Handler for bulk enable (POST /api/backends/enable)
-	mux.HandleFunc("/api/backends/enable", g.handleBulkSetBackendEnable(true))
+// overviewRecords summarizes the records of a zone the credential can access for the overview.
+func overviewRecords(cred *APICredential, zone string, recs map[string]*Record) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, rec := range recs {
+		if !cred.allowsRecord(zone, rec.Owner) {
+			continue
+		}
+		rec.mutex.RLock()
+		status := statusUnhealthy
+		var backends []map[string]interface{}
+		for _, be := range rec.Backends {
+			beMap, healthy := overviewBackend(be)
+			if healthy {
+				status = statusHealthy
+			}
+			backends = append(backends, beMap)
+		}
+		records = append(records, map[string]interface{}{
+			"record":   rec.Fqdn,
+			"status":   status,
+			"backends": backends,
+		})
+		rec.mutex.RUnlock()
+	}
+	return records
 }
 
-// bulkSetBackendEnable sets enable=true or false for all backends matching location or addressPrefix in the YAML config file.
-// Returns the number of backends modified and any error.
-func bulkSetBackendEnable(yamlFile, location, addressPrefix string, tags []string, enable bool) ([]map[string]string, error) {
-	data, err := os.ReadFile(yamlFile)
-	if err != nil {
-		return nil, err
+// overviewBackend summarizes a backend for the overview and reports whether it is healthy.
+func overviewBackend(b BackendInterface) (map[string]interface{}, bool) {
+	inMaintenance := b.InMaintenance()
+	windows := b.GetMaintenanceWindows()
+	state := b.getState()
+	consecutiveOK, consecutiveFail := b.GetConsecutiveResults()
+	healthy := state.Alive && b.IsEnabled() && !inMaintenance
+	aliveStr := statusUnhealthy
+	if healthy {
+		aliveStr = statusHealthy
 	}
-	var raw map[string]interface{}
-	if err := yaml.Unmarshal(data, &raw); err != nil {
-		return nil, err
+	beMap := map[string]interface{}{
+		"address":               b.GetAddress(),
+		"alive":                 aliveStr,
+		"last_healthcheck":      state.LastHealthcheck.Format(time.RFC3339),
+		"consecutive_successes": consecutiveOK,
+		"consecutive_failures":  consecutiveFail,
+		"maintenance":           inMaintenance,
 	}
-	records, ok := raw["records"].(map[string]interface{})
-	if !ok {
-		return nil, err
+	if len(windows) > 0 {
+		beMap["maintenance_windows"] = windows
 	}
-	var modified []map[string]string
-	for fqdn, rec := range records {
-		recMap, ok := rec.(map[string]interface{})
+	return beMap, healthy
+}
+
+// handleRecordDetail returns a handler with the full state of a record (GET /api/records/{fqdn})
+// or of one of its backends (GET /api/records/{fqdn}/backends/{address}).
+// The backends of a record can be filtered with the tag, location and health query parameters.
+func (g *GSLB) handleRecordDetail() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
 		if !ok {
-			continue
+			return
 		}
-		backends, ok := recMap["backends"].([]interface{})
-		if !ok {
-			continue
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET is supported."})
+			return
 		}
-		for _, be := range backends {
-			beMap, ok := be.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			addr, _ := beMap["address"].(string)
-			loc, _ := beMap["location"].(string)
-			tagsIface := beMap["tags"]
-			var beTags []string
-			if tagsIface != nil {
-				switch v := tagsIface.(type) {
-				case []interface{}:
-					for _, tag := range v {
-						if s, ok := tag.(string); ok {
-							beTags = append(beTags, s)
-						}
-					}
-				case []string:
-					beTags = v
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/records/"), "/"), "/")
+		if parts[0] == "" || (len(parts) != 1 && (len(parts) != 3 || parts[1] != "backends")) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Not found"})
+			return
+		}
+		fqdn := dns.Fqdn(parts[0])
+
+		// The lock is not held while selecting: the round robin and location stages take it
+		g.Mutex.RLock()
+		rec, zone := g.findRecord(fqdn)
+		allowed := rec != nil && cred.allowsRecord(zone, rec.Owner)
+		g.Mutex.RUnlock()
+		if rec == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Record not found"})
+			return
+		}
+		if !allowed {
+			g.denyScope(w, r, cred)
+			return
+		}
+
+		if len(parts) == 3 {
+			rec.mutex.RLock()
+			defer rec.mutex.RUnlock()
+			for _, be := range rec.Backends {
+				if be.GetAddress() == parts[2] {
+					json.NewEncoder(w).Encode(backendDetail(be))
+					return
 				}
 			}
-			match := false
-			if location != "" && loc == location {
-				match = true
-			}
-			if addressPrefix != "" && strings.HasPrefix(addr, addressPrefix) {
-				match = true
-			}
-			if len(tags) > 0 && len(beTags) > 0 {
-				for _, t := range tags {
-					for _, btag := range beTags {
-						if t == btag {
-							match = true
-							break
-						}
-					}
-					if match {
-						break
-					}
-				}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Backend not found"})
+			return
+		}
+
+		// The answer pickResponse would give, for the client IP of the request unless set, previewed
+		// so that reading the record neither advances the round robin nor counts as a selection
+		clientIP := net.ParseIP(r.URL.Query().Get("client_ip"))
+		if clientIP == nil {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			clientIP = net.ParseIP(host)
+		}
+		recordType := dns.TypeA
+		if strings.EqualFold(r.URL.Query().Get("type"), "AAAA") {
+			recordType = dns.TypeAAAA
+		}
+		client := &ClientInfo{IP: clientIP}
+		if clientIP != nil {
+			client.PrefixLen = addressPrefix(clientIP)
+		}
+		selected, _, err := g.previewResponse(fqdn, recordType, client)
+		if selected == nil {
+			selected = []string{}
+		}
+
+		ttl := rec.GetTTL()
+		degraded := rec.isDegraded()
+		rec.mutex.RLock()
+		defer rec.mutex.RUnlock()
+		status := statusUnhealthy
+		backends := []map[string]interface{}{}
+		tag, location, health := r.URL.Query().Get("tag"), r.URL.Query().Get("location"), r.URL.Query().Get("health")
+		for _, be := range rec.Backends {
+			detail := backendDetail(be)
+			if detail["status"] == statusHealthy {
+				status = statusHealthy
 			}
-			if match {
-				beMap["enable"] = enable
-				modified = append(modified, map[string]string{
-					"record":  fqdn,
-					"address": addr,
-				})
+			if (tag != "" && !backendMatches(be, "", "", []string{tag})) ||
+				(location != "" && be.GetLocation() != location) ||
+				(health != "" && detail["status"] != health) {
+				continue
 			}
+			backends = append(backends, detail)
+		}
+		detail := map[string]interface{}{
+			"record":      rec.Fqdn,
+			"zone":        zone,
+			"status":      status,
+			"mode":        rec.Mode,
+			"policy":      rec.Policy,
+			"owner":       rec.Owner,
+			"description": rec.Description,
+			"record_ttl":  rec.RecordTTL,
+			"ttl":         ttl,
+			"degraded":    degraded,
+			"fallback":    rec.GetFallback(),
+			"selected":    selected,
+			"backends":    backends,
+		}
+		if err != nil {
+			detail["selection_error"] = err.Error()
 		}
-		recMap["backends"] = backends
+		json.NewEncoder(w).Encode(detail)
 	}
+}
 
-	var buf bytes.Buffer
-	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(2)
+// backendDetail returns the full state of a backend. Its status is healthy, unhealthy, disabled or maintenance.
+func backendDetail(b BackendInterface) map[string]interface{} {
+	inMaintenance := b.InMaintenance()
+	windows := b.GetMaintenanceWindows()
+	state := b.getState()
+	consecutiveOK, consecutiveFail := b.GetConsecutiveResults()
 
-	if err := encoder.Encode(raw); err != nil {
-		return nil, err
+	status := statusUnhealthy
+	switch {
+	case !b.IsEnabled():
+		status = statusDisabled
+	case inMaintenance:
+		status = statusMaintenance
+	case state.Alive:
+		status = statusHealthy
 	}
-	encoder.Close()
-
-	if err := os.WriteFile(yamlFile, buf.Bytes(), 0644); err != nil {
-		return nil, err
+	results := b.GetHealthCheckResults()
+	healthchecks := make([]map[string]interface{}, 0, len(b.GetHealthChecks()))
+	for i, hc := range b.GetHealthChecks() {
+		typ := hc.GetType()
+		check := map[string]interface{}{"type": typ}
+		if i < len(results) && results[i].Type == typ {
+			result := results[i]
+			check["result"] = "down"
+			if result.Passed {
+				check["result"] = "up"
+			}
+			if result.Error != "" {
+				check["error"] = result.Error
+			}
+		}
+		healthchecks = append(healthchecks, check)
+	}
+	detail := map[string]interface{}{
+		"address":               b.GetAddress(),
+		"description":           b.GetDescription(),
+		"status":                status,
+		"enabled":               b.IsEnabled(),
+		"alive":                 state.Alive,
+		"overridden":            b.isOverridden(),
+		"priority":              b.GetPriority(),
+		"weight":                b.GetWeight(),
+		"port":                  b.GetPort(),
+		"target":                b.GetTarget(),
+		"tags":                  b.GetTags(),
+		"location":              b.GetLocation(),
+		"country":               b.GetCountry(),
+		"city":                  b.GetCity(),
+		"asn":                   b.GetASN(),
+		"response_time":         b.GetResponseTime().String(),
+		"capacity":              b.GetCapacity(),
+		"last_healthcheck":      state.LastHealthcheck.Format(time.RFC3339),
+		"last_status_change":    b.GetLastStatusChange().Format(time.RFC3339),
+		"consecutive_successes": consecutiveOK,
+		"consecutive_failures":  consecutiveFail,
+		"rise":                  b.GetRise(),
+		"fall":                  b.GetFall(),
+		"healthcheck_policy":    b.GetHealthCheckPolicy(),
+		"healthchecks":          healthchecks,
+		"maintenance":           inMaintenance,
 	}
-	return modified, nil
+	if load, reported := b.GetLoad(); reported {
+		detail["load"] = load
+	}
+	if b.HasCoordinates() {
+		detail["coordinates"] = map[string]float64{"latitude": b.GetLatitude(), "longitude": b.GetLongitude()}
+	}
+	if resolved := b.GetResolvedAddresses(); len(resolved) > 0 {
+		detail["resolved_addresses"] = resolved
+	}
+	if len(windows) > 0 {
+		detail["maintenance_windows"] = windows
+	}
+	return detail
+}
+
+// RegisterAPIHandlers registers all API endpoints to the provided mux.
+func (g *GSLB) RegisterAPIHandlers(mux *http.ServeMux) {
+	// Handler for /api/overview
+	mux.HandleFunc("/api/overview", g.handleOverview())
+	mux.HandleFunc("/api/overview/", g.handleOverview())
+
+	// Handler for bulk disable (POST /api/backends/disable)
+	mux.HandleFunc("/api/backends/disable", g.handleBulkSetBackendEnable(false))
+	// Handler for bulk enable (POST /api/backends/enable)
+	mux.HandleFunc("/api/backends/enable", g.handleBulkSetBackendEnable(true))
+	// Handler for runtime overrides (GET, DELETE /api/overrides)
+	mux.HandleFunc("/api/overrides", g.handleOverrides())
+	// Handler for maintenance windows (GET, POST, DELETE /api/maintenance)
+	mux.HandleFunc("/api/maintenance", g.handleMaintenance())
+	// Handler for records and backends (GET, POST, PUT, DELETE /api/zones/{zone}/records/...)
+	mux.HandleFunc("/api/zones/", g.handleZoneRecords())
+	// Handler for record and backend details (GET /api/records/{fqdn}[/backends/{address}])
+	mux.HandleFunc("/api/records/", g.handleRecordDetail())
+	// Handler for the event stream (GET /api/events)
+	mux.HandleFunc("/api/events", g.handleEvents())
+	// Handler for the audit trail (GET /api/audit)
+	mux.HandleFunc("/api/audit", g.handleAudit())
+	// Handlers for the liveness and readiness probes (GET /healthz, /readyz)
+	mux.HandleFunc("/healthz", g.handleHealthz())
+	mux.HandleFunc("/readyz", g.handleReadyz())
 }
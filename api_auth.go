@@ -0,0 +1,267 @@
+package gslb
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// API roles.
+const (
+	RoleRead  = "read"  // GET requests only
+	RoleWrite = "write" // All requests
+)
+
+// APICredential is an API token or client certificate with its role. With zones or owners, the
+// credential only sees and changes the records of these zones and owners.
+type APICredential struct {
+	Name       string   `yaml:"name"`
+	Token      string   `yaml:"token"`       // Bearer token
+	TokenEnv   string   `yaml:"token_env"`   // Environment variable holding the token
+	CommonName string   `yaml:"common_name"` // Common name of a client certificate
+	Role       string   `yaml:"role"`
+	Zones      []string `yaml:"zones"`
+	Owners     []string `yaml:"owners"`
+}
+
+// apiAuthConfig is the content of the api_auth file.
+type apiAuthConfig struct {
+	Tokens  []*APICredential `yaml:"tokens"`
+	Clients []*APICredential `yaml:"clients"`
+}
+
+// loadAPIAuth reads the tokens and client certificates allowed to use the API.
+func loadAPIAuth(path string) ([]*APICredential, []*APICredential, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg apiAuthConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, err
+	}
+	for _, t := range cfg.Tokens {
+		if t.TokenEnv != "" {
+			t.Token = os.Getenv(t.TokenEnv)
+		}
+		if t.Token == "" {
+			return nil, nil, fmt.Errorf("token %q: token or token_env required", t.Name)
+		}
+		if err := t.validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, c := range cfg.Clients {
+		if c.CommonName == "" {
+			return nil, nil, fmt.Errorf("client %q: common_name required", c.Name)
+		}
+		if c.Name == "" {
+			c.Name = c.CommonName
+		}
+		if err := c.validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg.Tokens, cfg.Clients, nil
+}
+
+// validate checks the role and normalizes the zones of a credential.
+func (c *APICredential) validate() error {
+	if c.Role != RoleRead && c.Role != RoleWrite {
+		return fmt.Errorf("credential %q: role must be %s or %s, got %q", c.Name, RoleRead, RoleWrite, c.Role)
+	}
+	for i, zone := range c.Zones {
+		c.Zones[i] = strings.ToLower(dns.Fqdn(zone))
+	}
+	return nil
+}
+
+// loadCertPool reads a PEM bundle of CA certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
+
+// scoped reports whether the credential is restricted to some zones or owners.
+// A nil credential, used when authentication is disabled, has full access.
+func (c *APICredential) scoped() bool {
+	return c != nil && (len(c.Zones) > 0 || len(c.Owners) > 0)
+}
+
+// allowsZone reports whether the credential can access a zone.
+func (c *APICredential) allowsZone(zone string) bool {
+	return c == nil || len(c.Zones) == 0 || containsString(c.Zones, zone)
+}
+
+// allowsRecord reports whether the credential can access a record of a zone with an owner.
+func (c *APICredential) allowsRecord(zone, owner string) bool {
+	return c.allowsZone(zone) && (c == nil || len(c.Owners) == 0 || containsString(c.Owners, owner))
+}
+
+// recordAllowed reports whether a credential can access a record, unknown records are only
+// allowed to unscoped credentials. The caller must hold g.Mutex.
+func (g *GSLB) recordAllowed(c *APICredential, fqdn string) bool {
+	if !c.scoped() {
+		return true
+	}
+	record, zone := g.findRecord(fqdn)
+	return record != nil && c.allowsRecord(zone, record.Owner)
+}
+
+// allowedOverrides returns the backend overrides the credential can access.
+func (g *GSLB) allowedOverrides(c *APICredential) []BackendOverride {
+	overrides := g.listOverrides()
+	if !c.scoped() {
+		return overrides
+	}
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	allowed := overrides[:0]
+	for _, o := range overrides {
+		if g.recordAllowed(c, o.Record) {
+			allowed = append(allowed, o)
+		}
+	}
+	return allowed
+}
+
+// allowedMaintenance returns the maintenance windows created through the API the credential can access.
+func (g *GSLB) allowedMaintenance(c *APICredential) []BackendMaintenance {
+	list := g.listMaintenance()
+	if !c.scoped() {
+		return list
+	}
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	allowed := list[:0]
+	for _, m := range list {
+		if g.recordAllowed(c, m.Record) {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// maintenanceIDAllowed reports whether the credential can access all the backends of a maintenance window.
+func (g *GSLB) maintenanceIDAllowed(c *APICredential, id string) bool {
+	if !c.scoped() {
+		return true
+	}
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	for _, m := range g.listMaintenance() {
+		if m.ID == id && !g.recordAllowed(c, m.Record) {
+			return false
+		}
+	}
+	return true
+}
+
+// eventAllowed reports whether a credential can receive an event.
+func (g *GSLB) eventAllowed(c *APICredential, event Event) bool {
+	if !c.scoped() {
+		return true
+	}
+	if event.Record == "" {
+		return event.Zone != "" && c.allowsZone(event.Zone)
+	}
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	return g.recordAllowed(c, event.Record)
+}
+
+// authEnabled reports whether API requests must be authenticated.
+func (g *GSLB) authEnabled() bool {
+	return (g.APIBasicUser != "" && g.APIBasicPass != "") || len(g.APITokens) > 0 || len(g.APIClients) > 0
+}
+
+// authenticate returns the credential of a request: a verified client certificate, a bearer token
+// or the basic auth user, which has the write role.
+func (g *GSLB) authenticate(r *http.Request) *APICredential {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, c := range g.APIClients {
+			if c.CommonName == cn {
+				return c
+			}
+		}
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		for _, t := range g.APITokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+				return t
+			}
+		}
+		return nil
+	}
+	if g.APIBasicUser != "" && g.APIBasicPass != "" {
+		user, pass, ok := r.BasicAuth()
+		if ok && subtle.ConstantTimeCompare([]byte(user), []byte(g.APIBasicUser)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(g.APIBasicPass)) == 1 {
+			return &APICredential{Name: user, Role: RoleWrite}
+		}
+	}
+	return nil
+}
+
+// checkAuth authenticates a request and checks its role allows the method, writing the error if not.
+// It returns the credential of the request, nil if authentication is disabled.
+func (g *GSLB) checkAuth(w http.ResponseWriter, r *http.Request) (*APICredential, bool) {
+	if !g.authEnabled() {
+		return nil, true
+	}
+	c := g.authenticate(r)
+	if c == nil {
+		g.denyAPIRequest(w, r, http.StatusUnauthorized, "unauthenticated", "")
+		return nil, false
+	}
+	if c.Role != RoleWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		g.denyAPIRequest(w, r, http.StatusForbidden, "forbidden", c.Name)
+		return nil, false
+	}
+	return c, true
+}
+
+// denyAPIRequest logs and counts a denied request and writes the error.
+func (g *GSLB) denyAPIRequest(w http.ResponseWriter, r *http.Request, status int, reason, name string) {
+	if name == "" {
+		name = "anonymous"
+	}
+	log.Warningf("API %s %s denied for %s from %s: %s", r.Method, r.URL.Path, name, r.RemoteAddr, reason)
+	IncAPIAuthDenied(reason)
+	w.Header().Set("Content-Type", "application/json")
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="GSLB API"`)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Forbidden"})
+}
+
+// denyScope logs and counts a request on records outside of the scope of its credential.
+func (g *GSLB) denyScope(w http.ResponseWriter, r *http.Request, c *APICredential) {
+	g.denyAPIRequest(w, r, http.StatusForbidden, "forbidden", c.Name)
+}
+
+// scopeError logs and counts a change of a record outside of the scope of a credential.
+func scopeError(c *APICredential, fqdn string) error {
+	log.Warningf("API change of %s denied for %s: out of scope", fqdn, c.Name)
+	IncAPIAuthDenied("forbidden")
+	return &apiError{http.StatusForbidden, "Forbidden"}
+}
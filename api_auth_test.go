@@ -0,0 +1,170 @@
+package gslb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const authAPIZoneFile = `
+records:
+  a.example.com.:
+    owner: team-a
+    backends:
+      - address: 10.0.0.1
+  b.example.com.:
+    owner: team-b
+    backends:
+      - address: 10.0.0.2
+`
+
+func newAuthAPITestServer(t *testing.T) (*GSLB, *httptest.Server) {
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(authAPIZoneFile), 0644))
+	g := &GSLB{
+		Zones:        map[string]string{"example.com.": zoneFile},
+		APIBasicUser: "admin",
+		APIBasicPass: "secret",
+		APITokens: []*APICredential{
+			{Name: "viewer", Token: "read-token", Role: RoleRead},
+			{Name: "team-a", Token: "team-a-token", Role: RoleWrite, Owners: []string{"team-a"}},
+			{Name: "other-zone", Token: "other-zone-token", Role: RoleWrite, Zones: []string{"example.org."}},
+		},
+	}
+	assert.NoError(t, loadConfigFile(g, zoneFile, "example.com."))
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return g, ts
+}
+
+func doTokenRequest(t *testing.T, method, url, token, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestLoadAPIAuth(t *testing.T) {
+	t.Setenv("GSLB_TEST_TOKEN", "from-env")
+	file := filepath.Join(t.TempDir(), "auth.yml")
+	assert.NoError(t, os.WriteFile(file, []byte(`
+tokens:
+  - name: ci
+    token_env: GSLB_TEST_TOKEN
+    role: write
+    zones: [example.com]
+  - name: grafana
+    token: s3cr3t
+    role: read
+clients:
+  - common_name: ops.example.com
+    role: write
+`), 0644))
+	tokens, clients, err := loadAPIAuth(file)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "from-env", tokens[0].Token)
+	assert.Equal(t, []string{"example.com."}, tokens[0].Zones)
+	assert.Len(t, clients, 1)
+	assert.Equal(t, "ops.example.com", clients[0].Name)
+
+	invalid := []string{
+		"tokens:\n  - name: empty\n    role: read\n",
+		"tokens:\n  - name: unset\n    token_env: GSLB_TEST_UNSET_TOKEN\n    role: read\n",
+		"tokens:\n  - name: bad\n    token: x\n    role: admin\n",
+		"clients:\n  - name: nocn\n    role: read\n",
+	}
+	for _, content := range invalid {
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+		_, _, err := loadAPIAuth(file)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestAPIAuth_Roles(t *testing.T) {
+	_, ts := newAuthAPITestServer(t)
+	denied := testutil.ToFloat64(apiAuthDenied.WithLabelValues("unauthenticated"))
+	forbidden := testutil.ToFloat64(apiAuthDenied.WithLabelValues("forbidden"))
+
+	// Unknown tokens are rejected, basic auth still works
+	resp := doTokenRequest(t, http.MethodGet, ts.URL+"/api/overview", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, denied+1, testutil.ToFloat64(apiAuthDenied.WithLabelValues("unauthenticated")))
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/overview", nil)
+	req.SetBasicAuth("admin", "secret")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The read role can only GET
+	resp = doTokenRequest(t, http.MethodGet, ts.URL+"/api/records/a.example.com.", "read-token", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doTokenRequest(t, http.MethodPost, ts.URL+"/api/backends/disable", "read-token", `{"record":"a.example.com."}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, forbidden+1, testutil.ToFloat64(apiAuthDenied.WithLabelValues("forbidden")))
+}
+
+func TestAPIAuth_Scope(t *testing.T) {
+	g, ts := newAuthAPITestServer(t)
+
+	// The overview only lists the records of the owner
+	resp := doTokenRequest(t, http.MethodGet, ts.URL+"/api/overview", "team-a-token", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var overview map[string][]map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&overview))
+	assert.Len(t, overview["example.com."], 1)
+	assert.Equal(t, "a.example.com.", overview["example.com."][0]["record"])
+
+	resp = doTokenRequest(t, http.MethodGet, ts.URL+"/api/records/b.example.com.", "team-a-token", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Bulk changes skip the records of other owners
+	resp = doTokenRequest(t, http.MethodPost, ts.URL+"/api/backends/disable", "team-a-token", `{"address_prefix":"10.0.0."}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, g.Records["example.com."]["a.example.com."].Backends[0].IsEnabled())
+	assert.True(t, g.Records["example.com."]["b.example.com."].Backends[0].IsEnabled())
+
+	// Records of other owners cannot be changed, nor given to another owner
+	resp = doTokenRequest(t, http.MethodDelete, ts.URL+"/api/zones/example.com./records/b.example.com.", "team-a-token", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doTokenRequest(t, http.MethodPut, ts.URL+"/api/zones/example.com./records/a.example.com.", "team-a-token",
+		`{"owner":"team-b","backends":[{"address":"10.0.0.1"}]}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "team-a", g.Records["example.com."]["a.example.com."].Owner)
+
+	// Zones outside of the scope
+	resp = doTokenRequest(t, http.MethodGet, ts.URL+"/api/zones/example.com./records", "other-zone-token", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doTokenRequest(t, http.MethodGet, ts.URL+"/api/overview", "other-zone-token", "")
+	overview = nil
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&overview))
+	assert.Empty(t, overview)
+}
+
+func TestAPIAuth_ClientCertificate(t *testing.T) {
+	g := &GSLB{APIClients: []*APICredential{{Name: "ops", CommonName: "ops.example.com", Role: RoleRead}}}
+	r := httptest.NewRequest(http.MethodGet, "/api/overview", nil)
+	assert.Nil(t, g.authenticate(r))
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ops.example.com"}}}}}
+	assert.Equal(t, "ops", g.authenticate(r).Name)
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "unknown.example.com"}}}}}
+	assert.Nil(t, g.authenticate(r))
+}
@@ -0,0 +1,531 @@
+package gslb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// apiError is an error returned to the API client with its HTTP status.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+// writeAPIError writes err as a JSON error, with status 400 unless it is an apiError.
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.status
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// handleZoneRecords returns a handler to manage the records and backends of a zone:
+//
+//	GET, POST          /api/zones/{zone}/records
+//	GET, PUT, DELETE   /api/zones/{zone}/records/{fqdn}
+//	POST               /api/zones/{zone}/records/{fqdn}/backends
+//	PUT, DELETE        /api/zones/{zone}/records/{fqdn}/backends/{address}
+//
+// Records and backends use the zone file format. Changes are validated like the zone file and
+// applied live; with ?persist=true they are also written to the zone file.
+func (g *GSLB) handleZoneRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/zones/"), "/"), "/")
+		if len(parts) < 2 || parts[1] != "records" || len(parts) > 5 || (len(parts) > 3 && parts[3] != "backends") {
+			writeAPIError(w, &apiError{http.StatusNotFound, "Not found"})
+			return
+		}
+		zone := dns.Fqdn(parts[0])
+		if !cred.allowsZone(zone) {
+			g.denyScope(w, r, cred)
+			return
+		}
+		persist := r.URL.Query().Get("persist") == "true"
+
+		switch len(parts) {
+		case 2:
+			g.handleRecords(w, r, cred, zone, persist)
+		case 3:
+			g.handleRecord(w, r, cred, zone, dns.Fqdn(parts[2]), persist)
+		case 4:
+			g.handleBackends(w, r, cred, zone, dns.Fqdn(parts[2]), persist)
+		default:
+			g.handleBackend(w, r, cred, zone, dns.Fqdn(parts[2]), parts[4], persist)
+		}
+	}
+}
+
+// handleRecords lists (GET) or creates (POST) the records of a zone.
+func (g *GSLB) handleRecords(w http.ResponseWriter, r *http.Request, cred *APICredential, zone string, persist bool) {
+	switch r.Method {
+	case http.MethodGet:
+		g.Mutex.RLock()
+		defer g.Mutex.RUnlock()
+		cfg, ok := g.zoneConfigs[zone]
+		if !ok {
+			writeAPIError(w, &apiError{http.StatusNotFound, "Zone not found"})
+			return
+		}
+		records := cfg.Records
+		if cred.scoped() {
+			records = make(map[string]interface{})
+			for fqdn, data := range cfg.Records {
+				if rec, ok := g.Records[zone][fqdn]; ok && cred.allowsRecord(zone, rec.Owner) {
+					records[fqdn] = data
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"records": records})
+	case http.MethodPost:
+		body, ok := decodeConfigBody(w, r)
+		if !ok {
+			return
+		}
+		fqdn, _ := body["fqdn"].(string)
+		if fqdn == "" {
+			writeAPIError(w, fmt.Errorf("fqdn required"))
+			return
+		}
+		fqdn = dns.Fqdn(fqdn)
+		delete(body, "fqdn")
+		err := g.applyZoneChange(cred, zone, fqdn, persist, func(records map[string]interface{}) error {
+			if _, exists := records[fqdn]; exists {
+				return &apiError{http.StatusConflict, "Record already exists"}
+			}
+			records[fqdn] = body
+			return nil
+		})
+		g.auditRequest(r, cred, AuditEntry{Action: AuditRecordCreate, Zone: zone, Request: body, Changes: []AuditChange{{Record: fqdn}}}, err)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "record": fqdn})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET and POST are supported."})
+	}
+}
+
+// handleRecord returns (GET), replaces (PUT) or deletes (DELETE) a record.
+func (g *GSLB) handleRecord(w http.ResponseWriter, r *http.Request, cred *APICredential, zone, fqdn string, persist bool) {
+	var change func(records map[string]interface{}) error
+	var entry AuditEntry
+	switch r.Method {
+	case http.MethodGet:
+		g.Mutex.RLock()
+		defer g.Mutex.RUnlock()
+		cfg, ok := g.zoneConfigs[zone]
+		if !ok {
+			writeAPIError(w, &apiError{http.StatusNotFound, "Zone not found"})
+			return
+		}
+		data, ok := cfg.Records[fqdn]
+		if !ok {
+			writeAPIError(w, &apiError{http.StatusNotFound, "Record not found"})
+			return
+		}
+		if rec, ok := g.Records[zone][fqdn]; ok && !cred.allowsRecord(zone, rec.Owner) {
+			g.denyScope(w, r, cred)
+			return
+		}
+		json.NewEncoder(w).Encode(data)
+		return
+	case http.MethodPut:
+		body, ok := decodeConfigBody(w, r)
+		if !ok {
+			return
+		}
+		entry = AuditEntry{Action: AuditRecordUpdate, Request: body}
+		change = func(records map[string]interface{}) error {
+			if _, exists := records[fqdn]; !exists {
+				return &apiError{http.StatusNotFound, "Record not found"}
+			}
+			records[fqdn] = body
+			return nil
+		}
+	case http.MethodDelete:
+		entry = AuditEntry{Action: AuditRecordDelete}
+		change = func(records map[string]interface{}) error {
+			if _, exists := records[fqdn]; !exists {
+				return &apiError{http.StatusNotFound, "Record not found"}
+			}
+			delete(records, fqdn)
+			return nil
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET, PUT and DELETE are supported."})
+		return
+	}
+	err := g.applyZoneChange(cred, zone, fqdn, persist, change)
+	entry.Zone = zone
+	entry.Changes = []AuditChange{{Record: fqdn}}
+	g.auditRequest(r, cred, entry, err)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "record": fqdn})
+}
+
+// handleBackends adds (POST) a backend to a record.
+func (g *GSLB) handleBackends(w http.ResponseWriter, r *http.Request, cred *APICredential, zone, fqdn string, persist bool) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only POST is supported."})
+		return
+	}
+	body, ok := decodeConfigBody(w, r)
+	if !ok {
+		return
+	}
+	address, _ := body["address"].(string)
+	if address == "" {
+		writeAPIError(w, fmt.Errorf("address required"))
+		return
+	}
+	err := g.updateRecordBackends(cred, zone, fqdn, persist, func(backends []interface{}) ([]interface{}, error) {
+		if backendIndex(backends, address) >= 0 {
+			return nil, &apiError{http.StatusConflict, "Backend already exists"}
+		}
+		return append(backends, body), nil
+	})
+	g.auditRequest(r, cred, AuditEntry{Action: AuditBackendCreate, Zone: zone, Request: body, Changes: []AuditChange{{Record: fqdn, Address: address}}}, err)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "record": fqdn, "address": address})
+}
+
+// handleBackend replaces (PUT) or deletes (DELETE) a backend of a record.
+func (g *GSLB) handleBackend(w http.ResponseWriter, r *http.Request, cred *APICredential, zone, fqdn, address string, persist bool) {
+	var change func(backends []interface{}) ([]interface{}, error)
+	var entry AuditEntry
+	switch r.Method {
+	case http.MethodPut:
+		body, ok := decodeConfigBody(w, r)
+		if !ok {
+			return
+		}
+		if a, set := body["address"]; set && a != address {
+			writeAPIError(w, fmt.Errorf("address cannot be changed, delete the backend and create a new one"))
+			return
+		}
+		body["address"] = address
+		entry = AuditEntry{Action: AuditBackendUpdate, Request: body}
+		change = func(backends []interface{}) ([]interface{}, error) {
+			i := backendIndex(backends, address)
+			if i < 0 {
+				return nil, &apiError{http.StatusNotFound, "Backend not found"}
+			}
+			backends[i] = body
+			return backends, nil
+		}
+	case http.MethodDelete:
+		entry = AuditEntry{Action: AuditBackendDelete}
+		change = func(backends []interface{}) ([]interface{}, error) {
+			i := backendIndex(backends, address)
+			if i < 0 {
+				return nil, &apiError{http.StatusNotFound, "Backend not found"}
+			}
+			return append(backends[:i], backends[i+1:]...), nil
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only PUT and DELETE are supported."})
+		return
+	}
+	err := g.updateRecordBackends(cred, zone, fqdn, persist, change)
+	entry.Zone = zone
+	entry.Changes = []AuditChange{{Record: fqdn, Address: address}}
+	g.auditRequest(r, cred, entry, err)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "record": fqdn, "address": address})
+}
+
+// updateRecordBackends applies a change to the raw backends of a record.
+func (g *GSLB) updateRecordBackends(cred *APICredential, zone, fqdn string, persist bool, change func(backends []interface{}) ([]interface{}, error)) error {
+	return g.applyZoneChange(cred, zone, fqdn, persist, func(records map[string]interface{}) error {
+		record, ok := records[fqdn].(map[string]interface{})
+		if !ok {
+			return &apiError{http.StatusNotFound, "Record not found"}
+		}
+		backends, _ := record["backends"].([]interface{})
+		backends, err := change(backends)
+		if err != nil {
+			return err
+		}
+		record["backends"] = backends
+		return nil
+	})
+}
+
+// applyZoneChange applies a change of a record to a copy of the raw records of a zone, builds the
+// records like a zone file reload and updates the live ones. With persist, the records of the zone
+// file are updated first. A credential scoped to owners must own the record before and after the change.
+func (g *GSLB) applyZoneChange(cred *APICredential, zone, fqdn string, persist bool, change func(records map[string]interface{}) error) error {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	cfg, ok := g.zoneConfigs[zone]
+	if !ok {
+		return &apiError{http.StatusNotFound, "Zone not found"}
+	}
+	newCfg := cfg.clone()
+	if err := change(newCfg.Records); err != nil {
+		return err
+	}
+	newGSLB := &GSLB{}
+	if err := loadZoneConfig(newGSLB, newCfg, zone); err != nil {
+		return err
+	}
+	for _, rec := range []*Record{g.Records[zone][fqdn], newGSLB.Records[zone][fqdn]} {
+		if rec != nil && !cred.allowsRecord(zone, rec.Owner) {
+			return scopeError(cred, fqdn)
+		}
+	}
+
+	if persist {
+		file, ok := g.Zones[zone]
+		if !ok || file == "" {
+			return fmt.Errorf("zone %s has no zone file to persist to", zone)
+		}
+		data, err := editZoneFile(file, newCfg.Records)
+		if err != nil {
+			return &apiError{http.StatusInternalServerError, fmt.Sprintf("failed to encode zone file: %v", err)}
+		}
+		if err := writeFileAtomic(file, data); err != nil {
+			return &apiError{http.StatusInternalServerError, fmt.Sprintf("failed to write zone file: %v", err)}
+		}
+		// The change is applied below, the zone file watcher does not need to reload it
+		g.zoneWrites.Store(file, data)
+	}
+
+	g.updateRecords(context.Background(), newGSLB)
+	g.setZoneSerial(zone)
+	return nil
+}
+
+// decodeConfigBody decodes a JSON object in the zone file format, writing an error if it is invalid.
+func decodeConfigBody(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+		writeAPIError(w, &apiError{http.StatusBadRequest, "Invalid JSON"})
+		return nil, false
+	}
+	return body, true
+}
+
+// backendIndex returns the index of the backend with the given address in raw backends, or -1.
+func backendIndex(backends []interface{}, address string) int {
+	for i, b := range backends {
+		if m, ok := b.(map[string]interface{}); ok && m["address"] == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// ownZoneWrite reports whether a zone file still has the content last written by the records API,
+// which is already applied.
+func (g *GSLB) ownZoneWrite(file string) bool {
+	written, ok := g.zoneWrites.Load(file)
+	if !ok {
+		return false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil || !bytes.Equal(data, written.([]byte)) {
+		g.zoneWrites.Delete(file)
+		return false
+	}
+	return true
+}
+
+// editZoneFile returns the content of a zone file with its records replaced by the given ones.
+// Only the changed values are encoded again: the rest of the file, comments and key order
+// included, is kept as is.
+func editZoneFile(file string, records map[string]interface{}) ([]byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("zone file is not a YAML mapping")
+	}
+
+	// Compare the records as decoded from YAML, JSON numbers are floats
+	var data interface{}
+	encoded, err := yaml.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(encoded, &data); err != nil {
+		return nil, err
+	}
+
+	root := doc.Content[0]
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "records" {
+			continue
+		}
+		if root.Content[i+1], err = updateNode(root.Content[i+1], data); err != nil {
+			return nil, err
+		}
+		found = true
+		break
+	}
+	if !found {
+		value, err := updateNode(&yaml.Node{}, data)
+		if err != nil {
+			return nil, err
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "records"}, value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// updateNode returns node updated to data. The nodes of the values that did not change are kept,
+// new map keys are appended in sorted order.
+func updateNode(node *yaml.Node, data interface{}) (*yaml.Node, error) {
+	if nodeEquals(node, data) {
+		return node, nil
+	}
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		updated := *node
+		updated.Content = nil
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			v, ok := data[key]
+			if !ok {
+				continue
+			}
+			value, err := updateNode(node.Content[i+1], v)
+			if err != nil {
+				return nil, err
+			}
+			updated.Content = append(updated.Content, node.Content[i], value)
+		}
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			if mappingValue(node, key) == nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := updateNode(&yaml.Node{}, data[key])
+			if err != nil {
+				return nil, err
+			}
+			updated.Content = append(updated.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+		}
+		return &updated, nil
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode {
+			break
+		}
+		// Items are matched by content, so that adding or removing one keeps the others
+		updated := *node
+		updated.Content = nil
+		used := make([]bool, len(node.Content))
+		for i, item := range data {
+			var value *yaml.Node
+			for j, old := range node.Content {
+				if !used[j] && nodeEquals(old, item) {
+					used[j], value = true, old
+					break
+				}
+			}
+			if value == nil && i < len(node.Content) && !used[i] && len(data) == len(node.Content) {
+				v, err := updateNode(node.Content[i], item)
+				if err != nil {
+					return nil, err
+				}
+				used[i], value = true, v
+			}
+			if value == nil {
+				v, err := updateNode(&yaml.Node{}, item)
+				if err != nil {
+					return nil, err
+				}
+				value = v
+			}
+			updated.Content = append(updated.Content, value)
+		}
+		return &updated, nil
+	}
+
+	var value yaml.Node
+	if err := value.Encode(data); err != nil {
+		return nil, err
+	}
+	value.HeadComment, value.LineComment, value.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	return &value, nil
+}
+
+// nodeEquals reports whether a YAML node decodes to data.
+func nodeEquals(node *yaml.Node, data interface{}) bool {
+	if node.Kind == 0 {
+		return false
+	}
+	var current interface{}
+	return node.Decode(&current) == nil && reflect.DeepEqual(current, data)
+}
+
+// mappingValue returns the value of a key of a YAML mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package gslb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const recordsAPIZoneFile = `
+defaults:
+  record_ttl: 60
+records:
+  app.example.com.:
+    mode: failover
+    backends:
+      - address: 1.2.3.4
+        priority: 1
+`
+
+func newRecordsAPITestServer(t *testing.T) (*GSLB, *httptest.Server, string) {
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(recordsAPIZoneFile), 0644))
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}}
+	assert.NoError(t, loadConfigFile(g, zoneFile, "example.com."))
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return g, ts, zoneFile
+}
+
+func doAPIRequest(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAPIRecords_CRUD(t *testing.T) {
+	g, ts, zoneFile := newRecordsAPITestServer(t)
+	original, _ := os.ReadFile(zoneFile)
+
+	// Create a record, the zone defaults apply
+	resp := doAPIRequest(t, http.MethodPost, ts.URL+"/api/zones/example.com./records",
+		`{"fqdn":"web.example.com","mode":"round-robin","backends":[{"address":"10.0.0.1"},{"address":"10.0.0.2"}]}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	record := g.Records["example.com."]["web.example.com."]
+	assert.NotNil(t, record)
+	assert.Equal(t, "round-robin", record.Mode)
+	assert.Equal(t, 60, record.RecordTTL)
+	assert.Len(t, record.Backends, 2)
+
+	// Creating it twice is a conflict
+	resp = doAPIRequest(t, http.MethodPost, ts.URL+"/api/zones/example.com./records", `{"fqdn":"web.example.com."}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Get
+	resp = doAPIRequest(t, http.MethodGet, ts.URL+"/api/zones/example.com./records/web.example.com.", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var data map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&data))
+	assert.Equal(t, "round-robin", data["mode"])
+
+	// Replace, the record is updated in place
+	resp = doAPIRequest(t, http.MethodPut, ts.URL+"/api/zones/example.com./records/web.example.com.",
+		`{"mode":"failover","record_ttl":30,"backends":[{"address":"10.0.0.1"}]}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Same(t, record, g.Records["example.com."]["web.example.com."])
+	assert.Equal(t, "failover", record.Mode)
+	assert.Equal(t, 30, record.RecordTTL)
+	assert.Len(t, record.Backends, 1)
+
+	// Delete
+	resp = doAPIRequest(t, http.MethodDelete, ts.URL+"/api/zones/example.com./records/web.example.com.", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, g.Records["example.com."], "web.example.com.")
+	resp = doAPIRequest(t, http.MethodDelete, ts.URL+"/api/zones/example.com./records/web.example.com.", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Without persist the zone file is untouched
+	current, _ := os.ReadFile(zoneFile)
+	assert.Equal(t, string(original), string(current))
+}
+
+func TestAPIRecords_Validation(t *testing.T) {
+	g, ts, _ := newRecordsAPITestServer(t)
+
+	// Invalid values are rejected like in the zone file
+	resp := doAPIRequest(t, http.MethodPut, ts.URL+"/api/zones/example.com./records/app.example.com.",
+		`{"fallback":"unknown","backends":[{"address":"1.2.3.4"}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "all", g.Records["example.com."]["app.example.com."].Fallback)
+
+	// Records outside of the zone
+	resp = doAPIRequest(t, http.MethodPost, ts.URL+"/api/zones/example.com./records", `{"fqdn":"app.example.org."}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Unknown zone and invalid path
+	resp = doAPIRequest(t, http.MethodGet, ts.URL+"/api/zones/example.org./records", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doAPIRequest(t, http.MethodGet, ts.URL+"/api/zones/example.com./other", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Invalid JSON
+	resp = doAPIRequest(t, http.MethodPost, ts.URL+"/api/zones/example.com./records", `not json`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPIRecords_Backends(t *testing.T) {
+	g, ts, zoneFile := newRecordsAPITestServer(t)
+	base := ts.URL + "/api/zones/example.com./records/app.example.com./backends"
+
+	// Add
+	resp := doAPIRequest(t, http.MethodPost, base, `{"address":"1.2.3.5","priority":2}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	record := g.Records["example.com."]["app.example.com."]
+	assert.Len(t, record.Backends, 2)
+	resp = doAPIRequest(t, http.MethodPost, base, `{"address":"1.2.3.5"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = doAPIRequest(t, http.MethodPost, base, `{"priority":2}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Update, persisted to the zone file
+	resp = doAPIRequest(t, http.MethodPut, base+"/1.2.3.5?persist=true", `{"priority":5,"tags":["new"]}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 5, record.Backends[1].GetPriority())
+	assert.Equal(t, []string{"new"}, record.Backends[1].GetTags())
+	resp = doAPIRequest(t, http.MethodPut, base+"/1.2.3.5", `{"address":"1.2.3.6"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	data, err := os.ReadFile(zoneFile)
+	assert.NoError(t, err)
+	var persisted zoneConfig
+	assert.NoError(t, yaml.Unmarshal(data, &persisted))
+	assert.Equal(t, 60, persisted.Defaults["record_ttl"])
+	backends := persisted.Records["app.example.com."].(map[string]interface{})["backends"].([]interface{})
+	assert.Len(t, backends, 2)
+	assert.Equal(t, 5, backends[1].(map[string]interface{})["priority"])
+
+	// The persisted file loads like the original
+	reloaded := &GSLB{}
+	assert.NoError(t, loadConfigFile(reloaded, zoneFile, "example.com."))
+	assert.Len(t, reloaded.Records["example.com."]["app.example.com."].Backends, 2)
+
+	// Delete
+	resp = doAPIRequest(t, http.MethodDelete, base+"/1.2.3.5", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, record.Backends, 1)
+	resp = doAPIRequest(t, http.MethodDelete, base+"/1.2.3.5", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAPIRecords_PersistKeepsZoneFile(t *testing.T) {
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	original := `# Managed by ops
+defaults:
+  record_ttl: 60
+records:
+  # Main site
+  app.example.com.:
+    mode: failover
+    backends:
+      - address: 1.2.3.4 # primary
+        priority: 1
+      - address: 1.2.3.5
+        priority: 2
+  old.example.com.:
+    mode: failover
+    backends:
+      - address: 1.2.3.9
+`
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(original), 0644))
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}}
+	assert.NoError(t, loadConfigFile(g, zoneFile, "example.com."))
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	base := ts.URL + "/api/zones/example.com./records"
+
+	// Only the changed values are written again
+	resp := doAPIRequest(t, http.MethodPut, base+"/app.example.com./backends/1.2.3.5?persist=true", `{"priority":5}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doAPIRequest(t, http.MethodDelete, base+"/old.example.com.?persist=true", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doAPIRequest(t, http.MethodPost, base+"?persist=true", `{"fqdn":"new.example.com.","backends":[{"address":"10.0.0.1"}]}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	data, err := os.ReadFile(zoneFile)
+	assert.NoError(t, err)
+	assert.Equal(t, `# Managed by ops
+defaults:
+  record_ttl: 60
+records:
+  # Main site
+  app.example.com.:
+    mode: failover
+    backends:
+      - address: 1.2.3.4 # primary
+        priority: 1
+      - address: 1.2.3.5
+        priority: 5
+  new.example.com.:
+    backends:
+      - address: 10.0.0.1
+`, string(data))
+
+	// The zone file watcher skips the reload of the API own write, not a later edit
+	assert.True(t, g.ownZoneWrite(zoneFile))
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(original), 0644))
+	assert.False(t, g.ownZoneWrite(zoneFile))
+}
@@ -0,0 +1,119 @@
+package gslb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// apiIdleTimeout is how long an idle keep-alive connection to the API is kept open.
+const apiIdleTimeout = 120 * time.Second
+
+// apiShutdownTimeout bounds the graceful shutdown of the API server.
+const apiShutdownTimeout = 5 * time.Second
+
+// startAPI starts the API server. Listen errors, such as a port already in use, are returned so
+// CoreDNS reports them at startup. It does nothing if the server is already running.
+func (g *GSLB) startAPI() error {
+	g.apiMutex.Lock()
+	defer g.apiMutex.Unlock()
+	if g.apiServer != nil {
+		return nil
+	}
+
+	readTimeout, err := time.ParseDuration(g.APIReadTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid value for api_read_timeout: %v", g.APIReadTimeout)
+	}
+	writeTimeout, err := time.ParseDuration(g.APIWriteTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid value for api_write_timeout: %v", g.APIWriteTimeout)
+	}
+
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	// The requests are canceled on shutdown, so that event streams do not hold it
+	baseCtx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{
+		Handler:           mux,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       apiIdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+	server.RegisterOnShutdown(cancel)
+	server.Protocols = new(http.Protocols)
+	server.Protocols.SetHTTP1(true)
+	if g.APIHTTP2 {
+		server.Protocols.SetHTTP2(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	useTLS := g.APICertPath != "" && g.APIKeyPath != ""
+	if useTLS {
+		cert, err := tls.LoadX509KeyPair(g.APICertPath, g.APIKeyPath)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to load API certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		if g.APIClientCA != "" {
+			pool, err := loadCertPool(g.APIClientCA)
+			if err != nil {
+				cancel()
+				return fmt.Errorf("failed to load API client CA: %w", err)
+			}
+			// Clients without a certificate can still use a token or basic auth
+			server.TLSConfig.ClientCAs = pool
+			server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(g.APIListenAddr, g.APIListenPort))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to start API server: %w", err)
+	}
+	server.Addr = listener.Addr().String()
+	g.apiServer = server
+
+	go func() {
+		var err error
+		if useTLS {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("API server stopped: %v", err)
+		}
+	}()
+	log.Infof("API server listening on %s", server.Addr)
+	return nil
+}
+
+// stopAPI gracefully shuts the API server down, waiting for the running requests up to
+// apiShutdownTimeout. It does nothing if the server is not running.
+func (g *GSLB) stopAPI() error {
+	g.apiMutex.Lock()
+	server := g.apiServer
+	g.apiServer = nil
+	g.apiMutex.Unlock()
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warningf("API server graceful shutdown failed, closing it: %v", err)
+		return server.Close()
+	}
+	log.Infof("API server on %s stopped", server.Addr)
+	return nil
+}
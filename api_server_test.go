@@ -0,0 +1,138 @@
+package gslb
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPIServerTestGSLB() *GSLB {
+	return &GSLB{
+		APIListenAddr:   "127.0.0.1",
+		APIListenPort:   "0",
+		APIReadTimeout:  "10s",
+		APIWriteTimeout: "30s",
+		APIHTTP2:        true,
+	}
+}
+
+func TestAPIServer_Lifecycle(t *testing.T) {
+	g := newAPIServerTestGSLB()
+	assert.NoError(t, g.startAPI())
+	defer g.stopAPI()
+	addr := g.apiServer.Addr
+
+	// Starting twice is a no-op
+	assert.NoError(t, g.startAPI())
+	assert.Equal(t, addr, g.apiServer.Addr)
+
+	resp, err := http.Get("http://" + addr + "/api/overview")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A port already in use is reported
+	_, port, _ := net.SplitHostPort(addr)
+	other := newAPIServerTestGSLB()
+	other.APIListenPort = port
+	assert.ErrorContains(t, other.startAPI(), "failed to start API server")
+
+	// Once stopped, the port is released for the next instance
+	assert.NoError(t, g.stopAPI())
+	assert.Nil(t, g.apiServer)
+	assert.NoError(t, g.stopAPI())
+	assert.NoError(t, other.startAPI())
+	assert.NoError(t, other.stopAPI())
+}
+
+func TestAPIServer_HTTP2(t *testing.T) {
+	g := newAPIServerTestGSLB()
+	assert.NoError(t, g.startAPI())
+	defer g.stopAPI()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	resp, err := client.Get("http://" + g.apiServer.Addr + "/api/overview")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its key.
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "api.pem"), filepath.Join(dir, "api.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestAPIServer_TLS(t *testing.T) {
+	g := newAPIServerTestGSLB()
+	g.APICertPath, g.APIKeyPath = writeTestCertificate(t)
+	assert.NoError(t, g.startAPI())
+	defer g.stopAPI()
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}
+	resp, err := (&http.Client{Transport: transport}).Get("https://" + g.apiServer.Addr + "/api/overview")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// Certificate errors are reported at startup
+	other := newAPIServerTestGSLB()
+	other.APICertPath, other.APIKeyPath = g.APIKeyPath, g.APICertPath
+	assert.ErrorContains(t, other.startAPI(), "failed to load API certificate")
+}
+
+func TestAPIServer_ShutdownWithEventStream(t *testing.T) {
+	g := newAPIServerTestGSLB()
+	g.APIWriteTimeout = "100ms"
+	assert.NoError(t, g.startAPI())
+
+	resp, err := http.Get("http://" + g.apiServer.Addr + "/api/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The stream is not cut by the write timeout
+	time.Sleep(200 * time.Millisecond)
+	events.Publish(Event{Type: EventConfigReload, Zone: "example.com.", Status: "success"})
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: config_reload\n", line)
+
+	// Open streams do not delay the shutdown
+	start := time.Now()
+	assert.NoError(t, g.stopAPI())
+	assert.Less(t, time.Since(start), apiShutdownTimeout)
+}
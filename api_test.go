@@ -7,10 +7,14 @@ import (
 	"testing"
 
 	"encoding/base64"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -144,8 +148,9 @@ func TestAPIDisableBackendsEndpoint(t *testing.T) {
 	f.Close()
 
 	g := &GSLB{
-		Zones: map[string]string{"test": f.Name()},
+		Zones: map[string]string{"example.com.": f.Name()},
 	}
+	assert.NoError(t, loadConfigFile(g, f.Name(), "example.com."))
 	mux := http.NewServeMux()
 	g.RegisterAPIHandlers(mux)
 	ts := httptest.NewServer(mux)
@@ -183,7 +188,6 @@ func TestAPIDisableBackendsEndpoint(t *testing.T) {
 	}
 
 	// Désactivation par préfixe d'IP
-	_ = os.WriteFile(f.Name(), []byte(tempYaml), 0644) // reset file
 	resp2, err := http.Post(ts.URL+"/api/backends/disable", "application/json", strings.NewReader(`{"address_prefix":"1.2.3."}`))
 	assert.NoError(t, err)
 	defer resp2.Body.Close()
@@ -212,6 +216,14 @@ func TestAPIDisableBackendsEndpoint(t *testing.T) {
 		assert.True(t, found, "Expected backend %+v not found in response", expected)
 	}
 
+	// Changes apply in memory, the zone file is left untouched
+	for _, be := range g.Records["example.com."]["test.example.com."].Backends {
+		assert.False(t, be.IsEnabled(), be.GetAddress())
+	}
+	data, err := os.ReadFile(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, tempYaml, string(data))
+
 	// Cas d'erreur : mauvais body
 	resp3, err := http.Post(ts.URL+"/api/backends/disable", "application/json", strings.NewReader(`{}`))
 	assert.NoError(t, err)
@@ -252,8 +264,9 @@ func TestAPIEnableBackendsEndpoint(t *testing.T) {
 	f.Close()
 
 	g := &GSLB{
-		Zones: map[string]string{"test": f.Name()},
+		Zones: map[string]string{"example.com.": f.Name()},
 	}
+	assert.NoError(t, loadConfigFile(g, f.Name(), "example.com."))
 	mux := http.NewServeMux()
 	g.RegisterAPIHandlers(mux)
 	ts := httptest.NewServer(mux)
@@ -291,7 +304,6 @@ func TestAPIEnableBackendsEndpoint(t *testing.T) {
 	}
 
 	// Enable by IP prefix
-	_ = os.WriteFile(f.Name(), []byte(tempYaml), 0644) // reset file
 	resp2, err := http.Post(ts.URL+"/api/backends/enable", "application/json", strings.NewReader(`{"address_prefix":"1.2.3."}`))
 	assert.NoError(t, err)
 	defer resp2.Body.Close()
@@ -351,10 +363,11 @@ func TestAPIBackendsAuthRequired(t *testing.T) {
 	f.Close()
 
 	g := &GSLB{
-		Zones:        map[string]string{"test": f.Name()},
+		Zones:        map[string]string{"example.com.": f.Name()},
 		APIBasicUser: "admin",
 		APIBasicPass: "secret",
 	}
+	assert.NoError(t, loadConfigFile(g, f.Name(), "example.com."))
 	mux := http.NewServeMux()
 	g.RegisterAPIHandlers(mux)
 	ts := httptest.NewServer(mux)
@@ -427,8 +440,9 @@ func TestAPIDisableBackendsByTags(t *testing.T) {
 	f.Close()
 
 	g := &GSLB{
-		Zones: map[string]string{"test": f.Name()},
+		Zones: map[string]string{"example.com.": f.Name()},
 	}
+	assert.NoError(t, loadConfigFile(g, f.Name(), "example.com."))
 	mux := http.NewServeMux()
 	g.RegisterAPIHandlers(mux)
 	ts := httptest.NewServer(mux)
@@ -465,6 +479,69 @@ func TestAPIDisableBackendsByTags(t *testing.T) {
 	}
 }
 
+func TestAPIOverridesEndpoint(t *testing.T) {
+	overridesFile := filepath.Join(t.TempDir(), "overrides.json")
+	backend1 := &Backend{Address: "1.2.3.4", Enable: true}
+	backend2 := &Backend{Address: "1.2.3.5", Enable: true}
+	g := &GSLB{
+		OverridesFile: overridesFile,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"test.example.com.": {Fqdn: "test.example.com.", Backends: []BackendInterface{backend1, backend2}}},
+		},
+	}
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Drain a single backend with a reason and an expiry
+	resp, err := http.Post(ts.URL+"/api/backends/disable", "application/json",
+		strings.NewReader(`{"record":"test.example.com","address_prefix":"1.2.3.4","reason":"maintenance","expires_in":"1h"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.False(t, backend1.IsEnabled())
+	assert.True(t, backend2.IsEnabled())
+	assert.FileExists(t, overridesFile)
+
+	// List
+	resp2, err := http.Get(ts.URL + "/api/overrides")
+	assert.NoError(t, err)
+	defer resp2.Body.Close()
+	var list struct {
+		Overrides []BackendOverride `json:"overrides"`
+	}
+	assert.NoError(t, json.NewDecoder(resp2.Body).Decode(&list))
+	assert.Len(t, list.Overrides, 1)
+	assert.Equal(t, "test.example.com.", list.Overrides[0].Record)
+	assert.Equal(t, "1.2.3.4", list.Overrides[0].Address)
+	assert.False(t, list.Overrides[0].Enable)
+	assert.Equal(t, "maintenance", list.Overrides[0].Reason)
+	assert.NotNil(t, list.Overrides[0].ExpiresAt)
+
+	// Invalid expiry
+	resp3, err := http.Post(ts.URL+"/api/backends/disable", "application/json", strings.NewReader(`{"record":"test.example.com.","expires_in":"soon"}`))
+	assert.NoError(t, err)
+	defer resp3.Body.Close()
+	assert.Equal(t, 400, resp3.StatusCode)
+
+	// Clear
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/overrides?record=test.example.com.&address=1.2.3.4", nil)
+	resp4, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp4.Body.Close()
+	assert.Equal(t, 200, resp4.StatusCode)
+	assert.True(t, backend1.IsEnabled())
+	assert.Empty(t, g.listOverrides())
+
+	// Clearing twice returns 404
+	req2, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/overrides?record=test.example.com.&address=1.2.3.4", nil)
+	resp5, err := http.DefaultClient.Do(req2)
+	assert.NoError(t, err)
+	defer resp5.Body.Close()
+	assert.Equal(t, 404, resp5.StatusCode)
+}
+
 // MockHealthCheckAPI always returns true and type "mock"
 type MockHealthCheckAPI struct{}
 
@@ -473,3 +550,197 @@ func (m *MockHealthCheckAPI) PerformCheck(backend *Backend, fqdn string, maxRetr
 }
 func (m *MockHealthCheckAPI) GetType() string                      { return "mock" }
 func (m *MockHealthCheckAPI) Equals(other GenericHealthCheck) bool { return true }
+
+func TestAPIMaintenanceEndpoint(t *testing.T) {
+	overridesFile := filepath.Join(t.TempDir(), "overrides.json")
+	backend1 := &Backend{Address: "1.2.3.4", Enable: true, Alive: true}
+	backend2 := &Backend{Address: "1.2.3.5", Enable: true, Alive: true}
+	g := &GSLB{
+		OverridesFile: overridesFile,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"test.example.com.": {Fqdn: "test.example.com.", Backends: []BackendInterface{backend1, backend2}}},
+		},
+	}
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Schedule a window running now on a single backend
+	now := time.Now().UTC()
+	body := fmt.Sprintf(`{"record":"test.example.com","address_prefix":"1.2.3.4","start":%q,"end":%q,"reason":"patching"}`,
+		now.Add(-time.Minute).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339))
+	resp, err := http.Post(ts.URL+"/api/maintenance", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	var created struct {
+		ID       string              `json:"id"`
+		Backends []map[string]string `json:"backends"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.NotEmpty(t, created.ID)
+	assert.Len(t, created.Backends, 1)
+	assert.True(t, backend1.InMaintenance())
+	assert.False(t, backend2.InMaintenance())
+	assert.FileExists(t, overridesFile)
+
+	// List
+	resp2, err := http.Get(ts.URL + "/api/maintenance")
+	assert.NoError(t, err)
+	defer resp2.Body.Close()
+	var list struct {
+		Maintenance []BackendMaintenance `json:"maintenance"`
+	}
+	assert.NoError(t, json.NewDecoder(resp2.Body).Decode(&list))
+	assert.Len(t, list.Maintenance, 1)
+	assert.Equal(t, created.ID, list.Maintenance[0].ID)
+	assert.Equal(t, "1.2.3.4", list.Maintenance[0].Address)
+	assert.Equal(t, "patching", list.Maintenance[0].Reason)
+
+	// Invalid recurrence
+	body = fmt.Sprintf(`{"record":"test.example.com.","start":%q,"end":%q,"recurrence":"weekly"}`,
+		now.Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339))
+	resp3, err := http.Post(ts.URL+"/api/maintenance", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp3.Body.Close()
+	assert.Equal(t, 400, resp3.StatusCode)
+
+	// Delete
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/maintenance?id="+created.ID, nil)
+	resp4, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp4.Body.Close()
+	assert.Equal(t, 200, resp4.StatusCode)
+	assert.False(t, backend1.InMaintenance())
+	assert.Empty(t, g.listMaintenance())
+
+	// Deleting twice returns 404
+	req2, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/maintenance?id="+created.ID, nil)
+	resp5, err := http.DefaultClient.Do(req2)
+	assert.NoError(t, err)
+	defer resp5.Body.Close()
+	assert.Equal(t, 404, resp5.StatusCode)
+}
+
+func TestAPIRecordDetailEndpoint(t *testing.T) {
+	rec := &Record{Fqdn: "test.example.com.", Mode: "failover", RecordTTL: 30, Fallback: FallbackAll}
+	backend1 := &Backend{
+		Address:            "1.2.3.4",
+		Priority:           1,
+		Enable:             true,
+		Alive:              true,
+		Tags:               []string{"prod"},
+		Location:           "eu",
+		Latitude:           48.85,
+		Longitude:          2.35,
+		CoordinatesSet:     true,
+		ResponseTime:       12 * time.Millisecond,
+		HealthChecks:       []GenericHealthCheck{&TCPHealthCheck{Port: 443}},
+		HealthCheckResults: []HealthCheckResult{{Type: "tcp/443", Passed: true}},
+	}
+	backend2 := &Backend{
+		Address:            "1.2.3.5",
+		Priority:           2,
+		Enable:             true,
+		Tags:               []string{"dr"},
+		Location:           "us",
+		HealthChecks:       []GenericHealthCheck{&TCPHealthCheck{Port: 443}},
+		HealthCheckResults: []HealthCheckResult{{Type: "tcp/443", Error: "connection"}},
+	}
+	rec.Backends = []BackendInterface{backend1, backend2}
+	g := &GSLB{Records: map[string]map[string]*Record{"example.com.": {rec.Fqdn: rec}}}
+
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	getJSON := func(path string) (int, map[string]interface{}) {
+		resp, err := http.Get(ts.URL + path)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	// Record
+	code, body := getJSON("/api/records/test.example.com")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "example.com.", body["zone"])
+	assert.Equal(t, "failover", body["mode"])
+	assert.Equal(t, float64(30), body["ttl"])
+	assert.Equal(t, "healthy", body["status"])
+	assert.Equal(t, []interface{}{"1.2.3.4"}, body["selected"])
+	assert.Len(t, body["backends"], 2)
+
+	// Filters
+	_, body = getJSON("/api/records/test.example.com./?health=unhealthy")
+	assert.Len(t, body["backends"], 1)
+	assert.Equal(t, "1.2.3.5", body["backends"].([]interface{})[0].(map[string]interface{})["address"])
+	_, body = getJSON("/api/records/test.example.com.?tag=prod&location=eu")
+	assert.Len(t, body["backends"], 1)
+	_, body = getJSON("/api/records/test.example.com.?location=asia")
+	assert.Len(t, body["backends"], 0)
+
+	// Backend
+	code, body = getJSON("/api/records/test.example.com./backends/1.2.3.4")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "healthy", body["status"])
+	assert.Equal(t, "12ms", body["response_time"])
+	assert.Equal(t, map[string]interface{}{"latitude": 48.85, "longitude": 2.35}, body["coordinates"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "tcp/443", "result": "up"}}, body["healthchecks"])
+
+	_, body = getJSON("/api/records/test.example.com./backends/1.2.3.5")
+	assert.Equal(t, "unhealthy", body["status"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "tcp/443", "result": "down", "error": "connection"}}, body["healthchecks"])
+
+	// Not found
+	code, _ = getJSON("/api/records/unknown.example.com.")
+	assert.Equal(t, 404, code)
+	code, _ = getJSON("/api/records/test.example.com./backends/9.9.9.9")
+	assert.Equal(t, 404, code)
+	code, _ = getJSON("/api/records/test.example.com./other/1.2.3.4")
+	assert.Equal(t, 404, code)
+
+	// Reading a roundrobin record previews the next answer without advancing the rotation
+	rr := &Record{Fqdn: "rr.example.com.", Mode: "roundrobin", RecordTTL: 30, Backends: []BackendInterface{
+		&Backend{Address: "1.2.3.6", Enable: true, Alive: true},
+		&Backend{Address: "1.2.3.7", Enable: true, Alive: true},
+	}}
+	g.Mutex.Lock()
+	g.Records["example.com."][rr.Fqdn] = rr
+	g.Mutex.Unlock()
+	for i := 0; i < 2; i++ {
+		code, body = getJSON("/api/records/rr.example.com.")
+		assert.Equal(t, 200, code)
+		assert.Equal(t, []interface{}{"1.2.3.6"}, body["selected"])
+	}
+	ips, _, err := g.pickResponse(rr.Fqdn, dns.TypeA, &ClientInfo{IP: net.ParseIP("192.0.2.1")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3.6"}, ips)
+	_, body = getJSON("/api/records/rr.example.com.")
+	assert.Equal(t, []interface{}{"1.2.3.7"}, body["selected"])
+
+	// Backends are described through BackendInterface, whatever their implementation
+	wrapped := &Record{Fqdn: "wrapped.example.com.", Mode: "failover", RecordTTL: 30, Backends: []BackendInterface{
+		wrappedBackend{&Backend{Address: "1.2.3.8", Enable: true, Alive: true}},
+	}}
+	g.Mutex.Lock()
+	g.Records["example.com."][wrapped.Fqdn] = wrapped
+	g.Mutex.Unlock()
+	code, body = getJSON("/api/records/wrapped.example.com.")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "healthy", body["status"])
+	code, body = getJSON("/api/records/wrapped.example.com./backends/1.2.3.8")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "healthy", body["status"])
+	code, _ = getJSON("/api/overview")
+	assert.Equal(t, 200, code)
+}
+
+// wrappedBackend is a BackendInterface implementation other than *Backend.
+type wrappedBackend struct {
+	*Backend
+}
@@ -0,0 +1,169 @@
+package gslb
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// auditMaxEntries is the number of audit entries kept in memory for /api/audit.
+const auditMaxEntries = 1000
+
+// Audited actions.
+const (
+	AuditBackendsDisable    = "backends_disable"
+	AuditBackendsEnable     = "backends_enable"
+	AuditOverrideClear      = "override_clear"
+	AuditMaintenanceAdd     = "maintenance_add"
+	AuditMaintenanceDelete  = "maintenance_delete"
+	AuditRecordCreate       = "record_create"
+	AuditRecordUpdate       = "record_update"
+	AuditRecordDelete       = "record_delete"
+	AuditBackendCreate      = "backend_create"
+	AuditBackendUpdate      = "backend_update"
+	AuditBackendDelete      = "backend_delete"
+	AuditConfigReload       = "config_reload"
+	AuditHealthcheckProfile = "healthcheck_profiles_reload"
+)
+
+// AuditEntry is a change made through the API or by a config reload.
+type AuditEntry struct {
+	Time      time.Time     `json:"time"`
+	Action    string        `json:"action"`
+	Principal string        `json:"principal"` // Credential name, anonymous without authentication, system for reloads
+	Source    string        `json:"source"`    // Client IP, or the reloaded file
+	Zone      string        `json:"zone,omitempty"`
+	Request   interface{}   `json:"request,omitempty"` // Filters or body of the request
+	Changes   []AuditChange `json:"changes,omitempty"`
+	Result    string        `json:"result"` // success or failure
+	Error     string        `json:"error,omitempty"`
+}
+
+// AuditChange is a record or backend changed by an audited action.
+type AuditChange struct {
+	Record       string `json:"record"`
+	Address      string `json:"address,omitempty"`
+	EnableBefore *bool  `json:"enable_before,omitempty"`
+	EnableAfter  *bool  `json:"enable_after,omitempty"`
+}
+
+// auditTrail keeps the latest audit entries in memory.
+type auditTrail struct {
+	mutex   sync.Mutex
+	entries []AuditEntry
+}
+
+// enableChange returns the change of the enable state of a backend.
+func enableChange(fqdn string, backend BackendInterface, before bool) AuditChange {
+	after := backend.IsEnabled()
+	return AuditChange{Record: fqdn, Address: backend.GetAddress(), EnableBefore: &before, EnableAfter: &after}
+}
+
+// audit records an entry in memory and appends it to the audit file if configured.
+// It does not take g.Mutex, reloads call it while holding it.
+func (g *GSLB) audit(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Result == "" {
+		entry.Result = "success"
+	}
+	g.auditTrail.mutex.Lock()
+	defer g.auditTrail.mutex.Unlock()
+	g.auditTrail.entries = append(g.auditTrail.entries, entry)
+	if len(g.auditTrail.entries) > auditMaxEntries {
+		g.auditTrail.entries = g.auditTrail.entries[len(g.auditTrail.entries)-auditMaxEntries:]
+	}
+
+	if g.AuditFile == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("Failed to encode audit entry: %v", err)
+		return
+	}
+	// The file is reopened for every entry so it can be rotated
+	f, err := os.OpenFile(g.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		log.Errorf("Failed to open audit file: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Errorf("Failed to write audit file: %v", err)
+	}
+}
+
+// auditRequest records an API change with the principal and source of the request.
+func (g *GSLB) auditRequest(r *http.Request, cred *APICredential, entry AuditEntry, err error) {
+	entry.Principal = "anonymous"
+	if cred != nil {
+		entry.Principal = cred.Name
+	}
+	entry.Source = r.RemoteAddr
+	if host, _, splitErr := net.SplitHostPort(r.RemoteAddr); splitErr == nil {
+		entry.Source = host
+	}
+	if err != nil {
+		entry.Result = "failure"
+		entry.Error = err.Error()
+	}
+	g.audit(entry)
+}
+
+// auditEntries returns the latest audit entries, oldest first, matching the action and principal if set.
+func (g *GSLB) auditEntries(action, principal string, limit int) []AuditEntry {
+	g.auditTrail.mutex.Lock()
+	defer g.auditTrail.mutex.Unlock()
+	entries := []AuditEntry{}
+	for i := len(g.auditTrail.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := g.auditTrail.entries[i]
+		if (action != "" && e.Action != action) || (principal != "" && e.Principal != principal) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// handleAudit returns a handler listing the latest audit entries (GET /api/audit?action=&principal=&limit=).
+// Credentials scoped to zones or owners cannot read the audit trail.
+func (g *GSLB) handleAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET is supported."})
+			return
+		}
+		if cred.scoped() {
+			g.denyScope(w, r, cred)
+			return
+		}
+		limit := 100
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive integer"})
+				return
+			}
+			limit = n
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entries": g.auditEntries(r.URL.Query().Get("action"), r.URL.Query().Get("principal"), limit),
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package gslb
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIAudit_Mutations(t *testing.T) {
+	dir := t.TempDir()
+	zoneFile := filepath.Join(dir, "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(recordsAPIZoneFile), 0644))
+	g := &GSLB{
+		Zones:        map[string]string{"example.com.": zoneFile},
+		APIBasicUser: "admin",
+		APIBasicPass: "secret",
+		AuditFile:    filepath.Join(dir, "audit.log"),
+	}
+	assert.NoError(t, loadConfigFile(g, zoneFile, "example.com."))
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	doAuth := func(method, url, body string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.SetBasicAuth("admin", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := doAuth(http.MethodPost, ts.URL+"/api/backends/disable", `{"record":"app.example.com.","reason":"drain"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doAuth(http.MethodDelete, ts.URL+"/api/zones/example.com./records/unknown.example.com.", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	entries := g.auditEntries("", "", 10)
+	assert.Len(t, entries, 2)
+	disable := entries[0]
+	assert.Equal(t, AuditBackendsDisable, disable.Action)
+	assert.Equal(t, "admin", disable.Principal)
+	assert.Equal(t, "127.0.0.1", disable.Source)
+	assert.Equal(t, "success", disable.Result)
+	assert.Len(t, disable.Changes, 1)
+	assert.Equal(t, "1.2.3.4", disable.Changes[0].Address)
+	assert.True(t, *disable.Changes[0].EnableBefore)
+	assert.False(t, *disable.Changes[0].EnableAfter)
+	assert.Equal(t, AuditRecordDelete, entries[1].Action)
+	assert.Equal(t, "failure", entries[1].Result)
+	assert.Equal(t, "Record not found", entries[1].Error)
+
+	// The audit file has one JSON entry per line
+	f, err := os.Open(g.AuditFile)
+	assert.NoError(t, err)
+	defer f.Close()
+	var lines []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		lines = append(lines, e)
+	}
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "drain", lines[0].Request.(map[string]interface{})["reason"])
+
+	// The API lists the entries, filtered by action
+	resp = doAuth(http.MethodGet, ts.URL+"/api/audit?action=backends_disable", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var data struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&data))
+	assert.Len(t, data.Entries, 1)
+	assert.Equal(t, AuditBackendsDisable, data.Entries[0].Action)
+	resp = doAuth(http.MethodGet, ts.URL+"/api/audit?limit=0", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAudit_ConfigReload(t *testing.T) {
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(recordsAPIZoneFile), 0644))
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}}
+	// Reloads are counted in a global metric, reset it for TestMetrics_ConfigReloads
+	t.Cleanup(configReloads.Reset)
+	assert.NoError(t, reloadConfig(g, zoneFile, "example.com."))
+	assert.NoError(t, os.WriteFile(zoneFile, []byte("records: ["), 0644))
+	assert.Error(t, reloadConfig(g, zoneFile, "example.com."))
+
+	entries := g.auditEntries(AuditConfigReload, "system", 10)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zoneFile, entries[0].Source)
+	assert.Equal(t, "example.com.", entries[0].Zone)
+	assert.Equal(t, "success", entries[0].Result)
+	assert.Equal(t, "failure", entries[1].Result)
+	assert.NotEmpty(t, entries[1].Error)
+}
+
+func TestAudit_MaxEntries(t *testing.T) {
+	g := &GSLB{}
+	for i := 0; i < auditMaxEntries+5; i++ {
+		g.audit(AuditEntry{Action: AuditConfigReload})
+	}
+	assert.Len(t, g.auditTrail.entries, auditMaxEntries)
+	assert.Len(t, g.auditEntries("", "", 3), 3)
+}
@@ -3,36 +3,86 @@ package gslb
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/miekg/dns"
 )
 
 // Backend represents an individual backend with health check settings.
 type Backend struct {
-	Fqdn            string               // Fully qualified domain name
-	Description     string               // Description of the backend
-	Address         string               // IP address or hostname
-	Priority        int                  // Priority for load balancing
-	Weight          int                  // Weight for weighted load balancing
-	Enable          bool                 // Enable or disable the backend
-	Tags            []string             // List of tags for filtering or grouping
-	HealthChecks    []GenericHealthCheck `yaml:"healthchecks"` // Health check configurations
-	Timeout         string               // Timeout for requests
-	Alive           bool                 // Indicates if the backend is alive
-	Country         string               // Country code for GeoIP
-	City            string               // City name for GeoIP
-	ASN             string               // ASN for GeoIP
-	Location        string               // location
-	Latitude        float64              // backend latitude for nearest routing
-	Longitude       float64              // backend longitude for nearest routing
-	CoordinatesSet  bool                 // indicates if latitude/longitude were provided
-	LastHealthcheck time.Time            // Last time a healthcheck was launched
-	ResponseTime    time.Duration        // Wall-clock duration of last health check run (used by fastest mode)
-	mutex           sync.RWMutex
+	Fqdn              string               // Fully qualified domain name
+	Description       string               // Description of the backend
+	Address           string               // IP address or hostname
+	Priority          int                  // Priority for load balancing
+	Weight            int                  // Weight for weighted load balancing
+	Port              int                  // Service port announced in SRV answers
+	Target            string               // Hostname announced as SRV target (defaults to the address for CNAME backends)
+	Enable            bool                 // Enable or disable the backend
+	Tags              []string             // List of tags for filtering or grouping
+	HealthChecks      []GenericHealthCheck `yaml:"healthchecks"` // Health check configurations
+	Timeout           string               // Timeout for requests
+	Alive             bool                 // Indicates if the backend is alive
+	Country           string               // Country code for GeoIP
+	City              string               // City name for GeoIP
+	ASN               string               // ASN for GeoIP
+	Location          string               // location
+	Latitude          float64              // backend latitude for nearest routing
+	Longitude         float64              // backend longitude for nearest routing
+	CoordinatesSet    bool                 // indicates if latitude/longitude were provided
+	LastHealthcheck   time.Time            // Last time a healthcheck was launched
+	ResponseTime      time.Duration        // Wall-clock duration of last health check run (used by fastest mode)
+	Capacity          float64              // Load at which least_loaded mode spills over to the next priority (0 for no limit)
+	Load              float64              // Load reported by the health checks of the last run (used by least_loaded mode)
+	LoadReported      bool                 // Indicates if a health check of the last run reported the load
+	ResolvedAddresses []string             // Addresses the CNAME target resolved to during the last health check
+	LastStatusChange  time.Time            // Last time the Alive status changed
+	Rise              int                  // Consecutive successful checks needed to become alive
+	Fall              int                  // Consecutive failed checks needed to become dead
+	ConsecutiveOK     int                  // Current number of consecutive successful checks
+	ConsecutiveFail   int                  // Current number of consecutive failed checks
+	HealthCheckPassed bool                 // Decision of the healthcheck policy for the last run
+	stateRestored     bool                 // Alive was restored from the state file: rise/fall apply from the first run
+	// HealthCheckPolicy aggregates the health check results (all, any, quorum:N, weighted:T)
+	HealthCheckPolicy  string
+	HealthCheckWeights map[string]int                // Weight per health check type for the weighted policy (default 1)
+	HealthCheckResults []HealthCheckResult           // Result of each health check of the last run, in order
+	pendingErrors      map[GenericHealthCheck]string // Failure reasons reported during the current run
+	checkAddresses     map[GenericHealthCheck]string // Resolved address each check of a CNAME backend is running against
+	pendingLoad        *float64                      // Load reported during the current run
+	overridden         bool                          // Enable is overridden at runtime through the API
+	configEnable       bool                          // Enable as set in the zone file while overridden
+	// Maintenance windows from the zone file, during which the backend is treated as disabled
+	Maintenance          []MaintenanceWindow
+	runtimeMaintenance   []MaintenanceWindow // Windows created through the API
+	maintenanceChecked   bool                // maintenanceActive is valid until maintenanceNextCheck
+	maintenanceActive    bool
+	maintenanceNextCheck time.Time
+	mutex                sync.RWMutex
 }
 
+// HealthCheckResult is the result of one of the health checks of a backend in the last run.
+type HealthCheckResult struct {
+	Type   string
+	Passed bool
+	Error  string // Failure reason (timeout, connection, protocol or other), empty if passed
+}
+
+// Health check aggregation policies.
+const (
+	HealthCheckPolicyAll      = "all"
+	HealthCheckPolicyAny      = "any"
+	HealthCheckPolicyQuorum   = "quorum"
+	HealthCheckPolicyWeighted = "weighted"
+)
+
+// HealthCheckAggregateType is the healthcheck type label carrying the aggregated policy decision.
+const HealthCheckAggregateType = "aggregate"
+
 func (b *Backend) Lock() {
 	b.mutex.Lock()
 }
@@ -46,6 +96,8 @@ func (b *Backend) GetFqdn() string {
 }
 
 func (b *Backend) SetFqdn(fqdn string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 	b.Fqdn = fqdn
 }
 
@@ -68,6 +120,14 @@ func (b *Backend) GetWeight() int {
 	return b.Weight
 }
 
+func (b *Backend) GetPort() int {
+	return b.Port
+}
+
+func (b *Backend) GetTarget() string {
+	return b.Target
+}
+
 func (b *Backend) IsEnabled() bool {
 	return b.Enable
 }
@@ -112,28 +172,202 @@ func (b *Backend) HasCoordinates() bool {
 	return b.CoordinatesSet
 }
 
+// IsCNAME returns true when the backend address is a hostname, in which case it is answered with a CNAME record.
+func (b *Backend) IsCNAME() bool {
+	return b.Address != "" && net.ParseIP(b.Address) == nil
+}
+
+// GetResolvedAddresses returns the addresses the CNAME target resolved to during the last health check.
+func (b *Backend) GetResolvedAddresses() []string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.ResolvedAddresses
+}
+
+// GetRise returns the number of consecutive successful checks needed to mark the backend alive.
+func (b *Backend) GetRise() int {
+	if b.Rise < 1 {
+		return 1
+	}
+	return b.Rise
+}
+
+// GetFall returns the number of consecutive failed checks needed to mark the backend dead.
+func (b *Backend) GetFall() int {
+	if b.Fall < 1 {
+		return 1
+	}
+	return b.Fall
+}
+
+// GetConsecutiveResults returns the current number of consecutive successful and failed checks.
+func (b *Backend) GetConsecutiveResults() (int, int) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.ConsecutiveOK, b.ConsecutiveFail
+}
+
+// GetHealthCheckPassed returns the decision of the healthcheck policy for the last run.
+func (b *Backend) GetHealthCheckPassed() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.HealthCheckPassed
+}
+
+// GetHealthCheckPolicy returns the health check aggregation policy, defaulting to all.
+func (b *Backend) GetHealthCheckPolicy() string {
+	if b.HealthCheckPolicy == "" {
+		return HealthCheckPolicyAll
+	}
+	return b.HealthCheckPolicy
+}
+
+// GetHealthCheckWeights returns the weight per health check type used by the weighted policy.
+func (b *Backend) GetHealthCheckWeights() map[string]int {
+	return b.HealthCheckWeights
+}
+
+// GetHealthCheckResults returns a copy of the results of each health check of the last run.
+func (b *Backend) GetHealthCheckResults() []HealthCheckResult {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]HealthCheckResult(nil), b.HealthCheckResults...)
+}
+
+// healthCheckTypeResults returns the result per health check type: passed only if every check of the type passed.
+func healthCheckTypeResults(results []HealthCheckResult) map[string]bool {
+	byType := make(map[string]bool, len(results))
+	for _, result := range results {
+		passed, seen := byType[result.Type]
+		byType[result.Type] = result.Passed && (passed || !seen)
+	}
+	return byType
+}
+
+// parseHealthCheckPolicy splits a policy into its name and threshold (0 for all and any).
+func parseHealthCheckPolicy(policy string) (string, int, error) {
+	name, value, hasValue := strings.Cut(policy, ":")
+	switch name {
+	case "", HealthCheckPolicyAll:
+		if hasValue {
+			return "", 0, fmt.Errorf("healthcheck policy %s does not take a threshold", policy)
+		}
+		return HealthCheckPolicyAll, 0, nil
+	case HealthCheckPolicyAny:
+		if hasValue {
+			return "", 0, fmt.Errorf("healthcheck policy %s does not take a threshold", policy)
+		}
+		return HealthCheckPolicyAny, 0, nil
+	case HealthCheckPolicyQuorum, HealthCheckPolicyWeighted:
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 1 {
+			return "", 0, fmt.Errorf("healthcheck policy %s requires a positive threshold (%s:N)", policy, name)
+		}
+		return name, threshold, nil
+	default:
+		return "", 0, fmt.Errorf("unsupported healthcheck policy: %s", policy)
+	}
+}
+
+// aggregateHealthChecks applies the health check policy to the results of a run.
+// A backend without health checks always passes.
+func (b *Backend) aggregateHealthChecks(results []bool) bool {
+	if len(results) == 0 {
+		return true
+	}
+	policy, threshold, err := parseHealthCheckPolicy(b.HealthCheckPolicy)
+	if err != nil {
+		// Validated when loading the configuration, keep the strictest behaviour otherwise
+		policy = HealthCheckPolicyAll
+	}
+
+	passed, score := 0, 0
+	for i, result := range results {
+		if !result {
+			continue
+		}
+		passed++
+		weight := 1
+		if w, ok := b.HealthCheckWeights[b.HealthChecks[i].GetType()]; ok {
+			weight = w
+		}
+		score += weight
+	}
+
+	switch policy {
+	case HealthCheckPolicyAny:
+		return passed > 0
+	case HealthCheckPolicyQuorum:
+		return passed >= threshold
+	case HealthCheckPolicyWeighted:
+		return score >= threshold
+	default:
+		return passed == len(results)
+	}
+}
+
+// formatHealthCheckResults renders the results as a "type=up|down" list in health check order.
+func formatHealthCheckResults(results []HealthCheckResult) string {
+	parts := make([]string, 0, len(results))
+	for _, result := range results {
+		state := "down"
+		if result.Passed {
+			state = "up"
+		}
+		parts = append(parts, result.Type+"="+state)
+	}
+	return strings.Join(parts, ",")
+}
+
 func (b *Backend) GetResponseTime() time.Duration {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 	return b.ResponseTime
 }
 
+// GetCapacity returns the load at which the backend is full, 0 for no limit.
+func (b *Backend) GetCapacity() float64 {
+	return b.Capacity
+}
+
+// GetLoad returns the load reported by the last health check run, and whether one was reported.
+func (b *Backend) GetLoad() (float64, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.Load, b.LoadReported
+}
+
+// GetLastStatusChange returns the last time the backend Alive status changed.
+func (b *Backend) GetLastStatusChange() time.Time {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.LastStatusChange
+}
+
 func (b *Backend) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var raw struct {
-		Description  string        `yaml:"description" default:""`
-		Address      string        `yaml:"address" default:"127.0.0.1"`
-		Priority     int           `yaml:"priority" default:"0"`
-		Weight       int           `yaml:"weight" default:"1"`
-		Enable       bool          `yaml:"enable" default:"true"`
-		Tags         []string      `yaml:"tags"`
-		Timeout      string        `yaml:"timeout" default:"5s"`
-		HealthChecks []HealthCheck `yaml:"healthchecks"`
-		Country      string        `yaml:"country"`
-		City         string        `yaml:"city"`
-		ASN          string        `yaml:"asn"`
-		Location     string        `yaml:"location"`
-		Latitude     *float64      `yaml:"latitude"`
-		Longitude    *float64      `yaml:"longitude"`
+		Description  string              `yaml:"description" default:""`
+		Address      string              `yaml:"address" default:"127.0.0.1"`
+		Priority     int                 `yaml:"priority" default:"0"`
+		Weight       int                 `yaml:"weight" default:"1"`
+		Capacity     float64             `yaml:"capacity" default:"0"`
+		Port         int                 `yaml:"port" default:"0"`
+		Target       string              `yaml:"target" default:""`
+		Enable       bool                `yaml:"enable" default:"true"`
+		Tags         []string            `yaml:"tags"`
+		Timeout      string              `yaml:"timeout" default:"5s"`
+		Rise         int                 `yaml:"rise" default:"0"`
+		Fall         int                 `yaml:"fall" default:"0"`
+		HCPolicy     string              `yaml:"healthcheck_policy" default:"all"`
+		HCWeights    map[string]int      `yaml:"healthcheck_weights"`
+		HealthChecks []HealthCheck       `yaml:"healthchecks"`
+		Country      string              `yaml:"country"`
+		City         string              `yaml:"city"`
+		ASN          string              `yaml:"asn"`
+		Location     string              `yaml:"location"`
+		Latitude     *float64            `yaml:"latitude"`
+		Longitude    *float64            `yaml:"longitude"`
+		Maintenance  []MaintenanceWindow `yaml:"maintenance"`
 	}
 	defaults.Set(&raw)
 	if err := unmarshal(&raw); err != nil {
@@ -143,13 +377,51 @@ func (b *Backend) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	b.Address = raw.Address
 	b.Priority = raw.Priority
 	b.Weight = raw.Weight
+	b.Capacity = raw.Capacity
+	b.Port = raw.Port
+	b.Target = raw.Target
 	b.Enable = raw.Enable
 	b.Tags = raw.Tags
 	b.Timeout = raw.Timeout
+	b.Rise = raw.Rise
+	b.Fall = raw.Fall
+	b.HealthCheckPolicy = raw.HCPolicy
+	b.HealthCheckWeights = raw.HCWeights
 	b.Country = raw.Country
 	b.City = raw.City
 	b.ASN = raw.ASN
 	b.Location = raw.Location
+	b.Maintenance = raw.Maintenance
+	if b.IsCNAME() {
+		if _, ok := dns.IsDomainName(b.Address); !ok {
+			return fmt.Errorf("backend %s: address must be an IP address or a valid hostname", raw.Address)
+		}
+	}
+	if raw.Rise < 0 || raw.Fall < 0 {
+		return fmt.Errorf("backend %s: rise and fall must not be negative", raw.Address)
+	}
+	if _, _, err := parseHealthCheckPolicy(raw.HCPolicy); err != nil {
+		return fmt.Errorf("backend %s: %w", raw.Address, err)
+	}
+	for typ, weight := range raw.HCWeights {
+		if weight < 0 {
+			return fmt.Errorf("backend %s: healthcheck weight for %s must not be negative", raw.Address, typ)
+		}
+	}
+	if raw.Capacity < 0 {
+		return fmt.Errorf("backend %s: capacity must not be negative", raw.Address)
+	}
+	if raw.Priority < 0 || raw.Priority > 65535 || raw.Weight < 0 || raw.Weight > 65535 {
+		return fmt.Errorf("backend %s: priority and weight must be between 0 and 65535", raw.Address)
+	}
+	if raw.Port < 0 || raw.Port > 65535 {
+		return fmt.Errorf("backend %s: port must be between 0 and 65535", raw.Address)
+	}
+	if b.Target != "" {
+		if _, ok := dns.IsDomainName(b.Target); !ok || net.ParseIP(b.Target) != nil {
+			return fmt.Errorf("backend %s: target must be a valid hostname", raw.Address)
+		}
+	}
 	if (raw.Latitude == nil) != (raw.Longitude == nil) {
 		return fmt.Errorf("backend %s: latitude and longitude must be set together", raw.Address)
 	}
@@ -192,7 +464,28 @@ func (b *Backend) updateBackend(newBackend BackendInterface) {
 		b.Weight = newBackend.GetWeight()
 	}
 
-	if b.Enable != newBackend.IsEnabled() {
+	if b.Capacity != newBackend.GetCapacity() {
+		log.Infof("[%s] backend %s updated, capacity changed from %g to %g", b.Fqdn, b.Address, b.Capacity, newBackend.GetCapacity())
+		b.Capacity = newBackend.GetCapacity()
+	}
+
+	if b.Port != newBackend.GetPort() {
+		log.Infof("[%s] backend %s updated, port changed from %d to %d", b.Fqdn, b.Address, b.Port, newBackend.GetPort())
+		b.Port = newBackend.GetPort()
+	}
+
+	if b.Target != newBackend.GetTarget() {
+		log.Infof("[%s] backend %s updated, target changed from %s to %s", b.Fqdn, b.Address, b.Target, newBackend.GetTarget())
+		b.Target = newBackend.GetTarget()
+	}
+
+	if b.overridden {
+		// Keep the runtime override, only track the zone file value to restore it later
+		if b.configEnable != newBackend.IsEnabled() {
+			log.Infof("[%s] backend %s updated, enable changed from %v to %v (overridden at runtime)", b.Fqdn, b.Address, b.configEnable, newBackend.IsEnabled())
+			b.configEnable = newBackend.IsEnabled()
+		}
+	} else if b.Enable != newBackend.IsEnabled() {
 		log.Infof("[%s] backend %s updated, enable changed from %v to %v", b.Fqdn, b.Address, b.Enable, newBackend.IsEnabled())
 		b.Enable = newBackend.IsEnabled()
 	}
@@ -234,6 +527,28 @@ func (b *Backend) updateBackend(newBackend BackendInterface) {
 		b.Longitude = newBackend.GetLongitude()
 	}
 
+	if b.GetRise() != newBackend.GetRise() || b.GetFall() != newBackend.GetFall() {
+		log.Infof("[%s] backend %s updated, rise/fall changed from %d/%d to %d/%d", b.Fqdn, b.Address, b.GetRise(), b.GetFall(), newBackend.GetRise(), newBackend.GetFall())
+		b.Rise = newBackend.GetRise()
+		b.Fall = newBackend.GetFall()
+	}
+
+	if b.GetHealthCheckPolicy() != newBackend.GetHealthCheckPolicy() {
+		log.Infof("[%s] backend %s updated, healthcheck policy changed from %s to %s", b.Fqdn, b.Address, b.GetHealthCheckPolicy(), newBackend.GetHealthCheckPolicy())
+		b.HealthCheckPolicy = newBackend.GetHealthCheckPolicy()
+	}
+
+	if !weightsEqual(b.HealthCheckWeights, newBackend.GetHealthCheckWeights()) {
+		log.Infof("[%s] backend %s updated, healthcheck weights changed", b.Fqdn, b.Address)
+		b.HealthCheckWeights = newBackend.GetHealthCheckWeights()
+	}
+
+	if !maintenanceEqual(b.Maintenance, newBackend.GetMaintenanceWindows()) {
+		log.Infof("[%s] backend %s updated, maintenance windows changed", b.Fqdn, b.Address)
+		b.Maintenance = newBackend.GetMaintenanceWindows()
+		b.maintenanceChecked = false
+	}
+
 	// Compare tags slice
 	if !tagsEqual(b.Tags, newBackend.GetTags()) {
 		log.Infof("[%s] backend %s updated, tags changed", b.Fqdn, b.Address)
@@ -251,12 +566,31 @@ func (b *Backend) runHealthChecks(maxRetries int, scrapeTimeout time.Duration) {
 	start := time.Now()
 	b.mutex.Lock()
 	b.LastHealthcheck = start
+	b.pendingErrors = make(map[GenericHealthCheck]string)
+	b.checkAddresses = make(map[GenericHealthCheck]string)
+	b.pendingLoad = nil
 	b.mutex.Unlock()
 	var wg sync.WaitGroup
 	results := make([]bool, len(b.HealthChecks))
+	timedOut := make([]bool, len(b.HealthChecks))
 
 	log.Debugf("[%s] starting health check for backend: %s", b.Fqdn, b.Address)
 
+	// CNAME backends are only considered alive if their target currently resolves
+	resolveFailed := false
+	var resolved []string
+	if b.IsCNAME() {
+		var err error
+		resolved, err = resolveBackendTarget(b.Address, scrapeTimeout)
+		if err != nil {
+			log.Debugf("[%s] failed to resolve CNAME target for backend: %s: %v", b.Fqdn, b.Address, err)
+			resolveFailed = true
+		}
+		b.mutex.Lock()
+		b.ResolvedAddresses = resolved
+		b.mutex.Unlock()
+	}
+
 	// Gather the list of health check types
 	var healthChecksList []string
 	for _, healthCheck := range b.HealthChecks {
@@ -277,7 +611,7 @@ func (b *Backend) runHealthChecks(maxRetries int, scrapeTimeout time.Duration) {
 
 			// Goroutine to perform the health check
 			go func() {
-				resultChan <- hc.PerformCheck(b, b.Fqdn, maxRetries)
+				resultChan <- b.performCheck(hc, resolved, maxRetries)
 			}()
 
 			// Wait for either the result or a timeout
@@ -286,6 +620,7 @@ func (b *Backend) runHealthChecks(maxRetries int, scrapeTimeout time.Duration) {
 			case <-ctx.Done():
 				log.Debugf("[%s] health check timed out for backend: %s, check: %s", b.Fqdn, b.Address, hc.GetType())
 				results[i] = false
+				timedOut[i] = true
 			}
 		}(i, hc)
 	}
@@ -299,29 +634,258 @@ func (b *Backend) runHealthChecks(maxRetries int, scrapeTimeout time.Duration) {
 	// Store old alive state for comparision
 	oldAlive := b.Alive
 
-	// Update the backend's Alive status
-	alive := true
-	for _, result := range results {
-		if !result {
-			alive = false
-			break
-		}
-	}
+	// Aggregate the result of this run according to the healthcheck policy
+	passed := !resolveFailed && b.aggregateHealthChecks(results)
+
+	// Update the backend's Alive status once rise/fall consecutive results are reached.
+	// The first run has no history and sets the initial state directly, unless it was restored.
 	b.mutex.Lock()
+	firstRun := b.ConsecutiveOK == 0 && b.ConsecutiveFail == 0 && !b.stateRestored
+	b.HealthCheckPassed = passed
+	if passed {
+		b.ConsecutiveOK++
+		b.ConsecutiveFail = 0
+	} else {
+		b.ConsecutiveFail++
+		b.ConsecutiveOK = 0
+	}
+	alive := oldAlive
+	switch {
+	case firstRun:
+		alive = passed
+	case !oldAlive && b.ConsecutiveOK >= b.GetRise():
+		alive = true
+	case oldAlive && b.ConsecutiveFail >= b.GetFall():
+		alive = false
+	}
 	b.Alive = alive
+	b.HealthCheckResults = make([]HealthCheckResult, len(results))
+	for i, result := range results {
+		hc := b.HealthChecks[i]
+		b.HealthCheckResults[i] = HealthCheckResult{Type: hc.GetType(), Passed: result}
+		if result {
+			continue
+		}
+		switch reason := b.pendingErrors[hc]; {
+		case timedOut[i]:
+			b.HealthCheckResults[i].Error = "timeout"
+		case reason != "":
+			b.HealthCheckResults[i].Error = reason
+		default:
+			b.HealthCheckResults[i].Error = "other"
+		}
+	}
 	b.ResponseTime = elapsed
+	b.LoadReported = b.pendingLoad != nil
+	if b.LoadReported {
+		b.Load = *b.pendingLoad
+	}
+	load, loadReported := b.Load, b.LoadReported
+	// The initial state is not a status change: it would hold the degraded TTL after every restart
+	if alive != oldAlive && !firstRun {
+		b.LastStatusChange = time.Now()
+	}
+	consecutiveOK, consecutiveFail := b.ConsecutiveOK, b.ConsecutiveFail
 	b.mutex.Unlock()
+	SetBackendConsecutiveChecks(b.Fqdn, b.Address, consecutiveOK, consecutiveFail)
+	if loadReported {
+		SetBackendLoad(b.Fqdn, b.Address, load)
+	} else {
+		DeleteBackendLoad(b.Fqdn, b.Address)
+	}
 
 	// Log backend health changes with higher log level
-	if b.Alive != oldAlive {
-		log.Infof("[%s] backend status change [address=%s]: alive changed from %v to %v", b.Fqdn, b.Address, oldAlive, b.Alive)
+	if alive != oldAlive {
+		log.Infof("[%s] backend status change [address=%s]: alive changed from %v to %v", b.Fqdn, b.Address, oldAlive, alive)
+		events.Publish(Event{Type: EventBackendStatus, Record: b.Fqdn, Address: b.Address, Status: healthStatus(alive), Previous: healthStatus(oldAlive)})
 	}
 
 	// Keep old log format for log parsing
 	log.Debugf("[%s] backend status [address=%s]: healthchecks=%s alive=%v", b.Fqdn, b.Address, healthChecksList, b.Alive)
 }
 
+// setEnableOverride overrides Enable at runtime, keeping the zone file value to restore it later.
+func (b *Backend) setEnableOverride(enable bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.overridden {
+		b.configEnable = b.Enable
+		b.overridden = true
+	}
+	if b.Enable != enable {
+		log.Infof("[%s] backend %s overridden, enable changed from %v to %v", b.Fqdn, b.Address, b.Enable, enable)
+	}
+	b.Enable = enable
+}
+
+// clearEnableOverride restores Enable to the zone file value.
+func (b *Backend) clearEnableOverride() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.overridden {
+		return
+	}
+	if b.Enable != b.configEnable {
+		log.Infof("[%s] backend %s override cleared, enable changed from %v to %v", b.Fqdn, b.Address, b.Enable, b.configEnable)
+	}
+	b.Enable = b.configEnable
+	b.overridden = false
+}
+
+// getConfigEnable returns Enable as set in the zone file, ignoring runtime overrides.
+// isOverridden reports whether Enable is overridden at runtime through the API.
+func (b *Backend) isOverridden() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.overridden
+}
+
+func (b *Backend) getConfigEnable() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.overridden {
+		return b.configEnable
+	}
+	return b.Enable
+}
+
+// InMaintenance reports whether a maintenance window of the backend is active.
+// The result is cached until the next window boundary, so this is cheap on the query path.
+func (b *Backend) InMaintenance() bool {
+	now := time.Now()
+	b.mutex.RLock()
+	if b.maintenanceChecked && (b.maintenanceNextCheck.IsZero() || now.Before(b.maintenanceNextCheck)) {
+		defer b.mutex.RUnlock()
+		return b.maintenanceActive
+	}
+	b.mutex.RUnlock()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	windows := append(append([]MaintenanceWindow{}, b.Maintenance...), b.runtimeMaintenance...)
+	active, next := maintenanceState(windows, now)
+	if b.maintenanceChecked && active != b.maintenanceActive {
+		log.Infof("[%s] backend %s maintenance changed from %v to %v", b.Fqdn, b.Address, b.maintenanceActive, active)
+	}
+	b.maintenanceActive = active
+	b.maintenanceNextCheck = next
+	b.maintenanceChecked = true
+	if active {
+		SetBackendMaintenance(b.Fqdn, b.Address, 1)
+	} else {
+		SetBackendMaintenance(b.Fqdn, b.Address, 0)
+	}
+	return active
+}
+
+// GetMaintenanceWindows returns the maintenance windows of the zone file and of the API.
+func (b *Backend) GetMaintenanceWindows() []MaintenanceWindow {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append(append([]MaintenanceWindow{}, b.Maintenance...), b.runtimeMaintenance...)
+}
+
+// setRuntimeMaintenance replaces the maintenance windows created through the API.
+func (b *Backend) setRuntimeMaintenance(windows []MaintenanceWindow) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if maintenanceEqual(b.runtimeMaintenance, windows) {
+		return
+	}
+	b.runtimeMaintenance = windows
+	b.maintenanceChecked = false
+}
+
+// performCheck runs a health check of the backend. The checks of a CNAME backend run against every address
+// its hostname resolved to, and pass only if they pass for all of them.
+func (b *Backend) performCheck(hc GenericHealthCheck, resolved []string, maxRetries int) bool {
+	if !b.IsCNAME() {
+		return hc.PerformCheck(b, b.Fqdn, maxRetries)
+	}
+	for _, address := range resolved {
+		b.mutex.Lock()
+		b.checkAddresses[hc] = address
+		b.mutex.Unlock()
+		if !hc.PerformCheck(b, b.Fqdn, maxRetries) {
+			return false
+		}
+	}
+	return len(resolved) > 0
+}
+
+// checkAddress returns the address a health check connects to: the resolved address it is running against
+// for CNAME backends, the backend address otherwise.
+func (b *Backend) checkAddress(hc GenericHealthCheck) string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if address, ok := b.checkAddresses[hc]; ok {
+		return address
+	}
+	return b.Address
+}
+
+// healthcheckFailed counts a health check failure and keeps its reason for the current run.
+func (b *Backend) healthcheckFailed(hc GenericHealthCheck, reason string) {
+	IncHealthcheckFailures(hc.GetType(), b.Address, reason)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.pendingErrors == nil {
+		b.pendingErrors = make(map[GenericHealthCheck]string)
+	}
+	b.pendingErrors[hc] = reason
+}
+
+// reportLoad keeps the load reported by a health check for the current run.
+func (b *Backend) reportLoad(load float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.pendingLoad = &load
+}
+
+// getState returns the health state of the backend to persist in the state file.
+func (b *Backend) getState() backendState {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return backendState{
+		Alive:           b.Alive,
+		LastHealthcheck: b.LastHealthcheck,
+		ResponseTime:    b.ResponseTime,
+	}
+}
+
+// setState restores the health state of the backend from the state file.
+func (b *Backend) setState(state backendState) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Alive = state.Alive
+	b.LastHealthcheck = state.LastHealthcheck
+	b.ResponseTime = state.ResponseTime
+	b.stateRestored = true
+}
+
+// lookupIPAddr resolves the hostnames of CNAME backends, replaced in tests.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// resolveBackendTarget resolves the hostname of a CNAME backend to its current IP addresses.
+func resolveBackendTarget(host string, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var resolved []string
+	for _, addr := range addrs {
+		resolved = append(resolved, addr.IP.String())
+	}
+	return resolved, nil
+}
+
 func (b *Backend) IsHealthy() bool {
+	if b.InMaintenance() {
+		return false
+	}
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 
@@ -341,6 +905,33 @@ func tagsEqual(t1, t2 []string) bool {
 	return true
 }
 
+// maintenanceEqual compares two lists of maintenance windows for equality.
+func maintenanceEqual(w1, w2 []MaintenanceWindow) bool {
+	if len(w1) != len(w2) {
+		return false
+	}
+	for i := range w1 {
+		if w1[i].ID != w2[i].ID || !w1[i].Start.Equal(w2[i].Start) || !w1[i].End.Equal(w2[i].End) ||
+			w1[i].Recurrence != w2[i].Recurrence || w1[i].Reason != w2[i].Reason {
+			return false
+		}
+	}
+	return true
+}
+
+// weightsEqual compares two health check weight maps for equality.
+func weightsEqual(w1, w2 map[string]int) bool {
+	if len(w1) != len(w2) {
+		return false
+	}
+	for typ, weight := range w1 {
+		if other, ok := w2[typ]; !ok || other != weight {
+			return false
+		}
+	}
+	return true
+}
+
 type BackendInterface interface {
 	GetFqdn() string
 	SetFqdn(fqdn string)
@@ -348,6 +939,8 @@ type BackendInterface interface {
 	GetAddress() string
 	GetPriority() int
 	GetWeight() int
+	GetPort() int
+	GetTarget() string
 	IsEnabled() bool
 	GetTags() []string
 	GetHealthChecks() []GenericHealthCheck
@@ -360,8 +953,29 @@ type BackendInterface interface {
 	GetLongitude() float64
 	HasCoordinates() bool
 	GetResponseTime() time.Duration
+	GetCapacity() float64
+	GetLoad() (float64, bool)
+	IsCNAME() bool
+	GetResolvedAddresses() []string
+	GetLastStatusChange() time.Time
+	GetRise() int
+	GetFall() int
+	GetConsecutiveResults() (int, int)
+	GetHealthCheckPassed() bool
+	GetHealthCheckPolicy() string
+	GetHealthCheckWeights() map[string]int
+	GetHealthCheckResults() []HealthCheckResult
 	IsHealthy() bool
 	runHealthChecks(retries int, timeout time.Duration)
+	getState() backendState
+	setEnableOverride(enable bool)
+	clearEnableOverride()
+	getConfigEnable() bool
+	isOverridden() bool
+	InMaintenance() bool
+	GetMaintenanceWindows() []MaintenanceWindow
+	setRuntimeMaintenance(windows []MaintenanceWindow)
+	setState(state backendState)
 	removeBackend()
 	updateBackend(newBackend BackendInterface)
 	Lock()
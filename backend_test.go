@@ -1,6 +1,8 @@
 package gslb
 
 import (
+	"context"
+	"net"
 	"testing"
 	"time"
 
@@ -22,6 +24,7 @@ latitude: 48.8566
 longitude: 2.3522
 enable: true
 timeout: "10s"
+capacity: 250
 healthchecks:
   - type: "http"
     params:
@@ -43,8 +46,97 @@ healthchecks:
 	assert.Equal(t, 48.8566, backend.Latitude)
 	assert.Equal(t, 2.3522, backend.Longitude)
 	assert.True(t, backend.CoordinatesSet)
+	assert.Equal(t, 250.0, backend.Capacity)
 	assert.Len(t, backend.HealthChecks, 1)
 	assert.IsType(t, &HTTPHealthCheck{}, backend.HealthChecks[0])
+
+	var negative Backend
+	assert.ErrorContains(t, yaml.Unmarshal([]byte("address: 127.0.0.1\ncapacity: -1\n"), &negative), "capacity must not be negative")
+}
+
+func TestBackend_UnmarshalYAML_CNAME(t *testing.T) {
+	var backend Backend
+	err := yaml.Unmarshal([]byte(`address: "lb-eu.cloudprovider.net"`), &backend)
+	assert.NoError(t, err)
+	assert.True(t, backend.IsCNAME())
+
+	var invalid Backend
+	err = yaml.Unmarshal([]byte(`address: "lb..cloudprovider.net"`), &invalid)
+	assert.Error(t, err)
+}
+
+func TestBackend_UnmarshalYAML_SRV(t *testing.T) {
+	var backend Backend
+	err := yaml.Unmarshal([]byte("address: \"10.0.0.1\"\nport: 5060\ntarget: \"sip-eu.example.com\""), &backend)
+	assert.NoError(t, err)
+	assert.Equal(t, 5060, backend.GetPort())
+	assert.Equal(t, "sip-eu.example.com", backend.GetTarget())
+
+	var invalid Backend
+	err = yaml.Unmarshal([]byte("address: \"10.0.0.1\"\nport: 70000"), &invalid)
+	assert.Error(t, err)
+
+	err = yaml.Unmarshal([]byte("address: \"10.0.0.1\"\ntarget: \"10.0.0.1\""), &invalid)
+	assert.Error(t, err)
+	// SRV priority, weight and port are 16-bit values
+	for _, field := range []string{"priority: -1", "priority: 65536", "weight: -1", "weight: 65536", "port: -1"} {
+		err = yaml.Unmarshal([]byte("address: \"10.0.0.1\"\n"+field), &invalid)
+		assert.Error(t, err, field)
+	}
+}
+
+func TestBackend_IsCNAME(t *testing.T) {
+	assert.False(t, (&Backend{Address: "192.168.1.1"}).IsCNAME())
+	assert.False(t, (&Backend{Address: "2001:db8::1"}).IsCNAME())
+	assert.False(t, (&Backend{}).IsCNAME())
+	assert.True(t, (&Backend{Address: "lb-eu.cloudprovider.net"}).IsCNAME())
+}
+
+func TestBackend_RunHealthChecks_CNAMEResolves(t *testing.T) {
+	defer func(orig func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = orig }(lookupIPAddr)
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		assert.Equal(t, "lb-eu.cloudprovider.net", host)
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.10")}, {IP: net.ParseIP("2001:db8::10")}}, nil
+	}
+
+	backend := &Backend{
+		Address:      "lb-eu.cloudprovider.net",
+		HealthChecks: []GenericHealthCheck{&MockHealthCheck{}},
+	}
+
+	backend.runHealthChecks(1, 5*time.Second)
+
+	assert.True(t, backend.Alive)
+	assert.Equal(t, []string{"192.0.2.10", "2001:db8::10"}, backend.GetResolvedAddresses())
+}
+
+func TestBackend_RunHealthChecks_CNAMEChecksResolvedAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	defer func(orig func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = orig }(lookupIPAddr)
+	resolved := []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return resolved, nil
+	}
+
+	backend := &Backend{
+		Address:      "lb-eu.cloudprovider.invalid",
+		HealthChecks: []GenericHealthCheck{&TCPHealthCheck{Port: port, Timeout: "1s"}},
+	}
+	backend.runHealthChecks(0, 5*time.Second)
+	assert.True(t, backend.Alive)
+
+	// Every resolved address must pass: nothing listens on 127.0.0.2
+	resolved = append(resolved, net.IPAddr{IP: net.ParseIP("127.0.0.2")})
+	backend = &Backend{
+		Address:      "lb-eu.cloudprovider.invalid",
+		HealthChecks: []GenericHealthCheck{&TCPHealthCheck{Port: port, Timeout: "1s"}},
+	}
+	backend.runHealthChecks(0, 5*time.Second)
+	assert.False(t, backend.Alive)
 }
 
 func TestBackend_RunHealthChecks(t *testing.T) {
@@ -63,6 +155,202 @@ func TestBackend_RunHealthChecks(t *testing.T) {
 	assert.True(t, backend.Alive)
 }
 
+func TestBackend_RunHealthChecks_LastStatusChange(t *testing.T) {
+	hc := &toggleHealthCheck{ok: true, typ: "toggle"}
+	backend := &Backend{
+		Address:      "127.0.0.1",
+		HealthChecks: []GenericHealthCheck{hc},
+		Fall:         1,
+	}
+
+	// The initial state at startup is not a transition
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.True(t, backend.Alive)
+	assert.True(t, backend.GetLastStatusChange().IsZero(), "Initial state should not be recorded as a transition")
+
+	hc.ok = false
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.False(t, backend.Alive)
+	changed := backend.GetLastStatusChange()
+	assert.False(t, changed.IsZero(), "Alive transition should be recorded")
+
+	// No transition, the timestamp must be kept
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.Equal(t, changed, backend.GetLastStatusChange())
+}
+
+// toggleHealthCheck returns the current value of ok, to simulate a flapping backend
+type toggleHealthCheck struct {
+	ok  bool
+	typ string
+}
+
+func (hc *toggleHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries int) bool {
+	return hc.ok
+}
+func (hc *toggleHealthCheck) GetType() string                      { return hc.typ }
+func (hc *toggleHealthCheck) Equals(other GenericHealthCheck) bool { return false }
+
+func TestBackend_RunHealthChecks_RiseFall(t *testing.T) {
+	hc := &toggleHealthCheck{ok: true, typ: "toggle"}
+	backend := &Backend{
+		Fqdn:         "rise.example.com.",
+		Address:      "127.0.0.1",
+		Rise:         2,
+		Fall:         3,
+		HealthChecks: []GenericHealthCheck{hc},
+	}
+
+	// The first run sets the initial state directly
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.True(t, backend.Alive)
+
+	// Two failures are not enough to go down
+	hc.ok = false
+	backend.runHealthChecks(1, 5*time.Second)
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.True(t, backend.Alive)
+	ok, fail := backend.GetConsecutiveResults()
+	assert.Equal(t, 0, ok)
+	assert.Equal(t, 2, fail)
+
+	// A single success resets the failure counter
+	hc.ok = true
+	backend.runHealthChecks(1, 5*time.Second)
+	hc.ok = false
+	for i := 0; i < 2; i++ {
+		backend.runHealthChecks(1, 5*time.Second)
+	}
+	assert.True(t, backend.Alive)
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.False(t, backend.Alive)
+
+	// Two consecutive successes are needed to come back
+	hc.ok = true
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.False(t, backend.Alive)
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.True(t, backend.Alive)
+}
+
+func TestBackend_RunHealthChecks_FailureReasons(t *testing.T) {
+	backend := &Backend{
+		Fqdn:    "reason.example.com.",
+		Address: "127.0.0.1",
+		HealthChecks: []GenericHealthCheck{
+			&TCPHealthCheck{Port: 1, Timeout: "1s"},
+			&GRPCHealthCheck{Port: 1, Timeout: time.Second},
+			&toggleHealthCheck{typ: "lua"},
+			&toggleHealthCheck{ok: true, typ: "ok"},
+		},
+		HealthCheckPolicy: HealthCheckPolicyAny,
+	}
+	backend.runHealthChecks(0, 5*time.Second)
+	assert.Equal(t, []HealthCheckResult{
+		{Type: "tcp/1", Error: "connection"},
+		{Type: "grpc", Error: "connection"},
+		{Type: "lua", Error: "other"},
+		{Type: "ok", Passed: true},
+	}, backend.GetHealthCheckResults())
+}
+
+func TestBackend_AggregateHealthChecks(t *testing.T) {
+	backend := &Backend{
+		HealthChecks: []GenericHealthCheck{
+			&toggleHealthCheck{typ: "https/443"},
+			&toggleHealthCheck{typ: "icmp"},
+			&toggleHealthCheck{typ: "tcp/22"},
+		},
+		HealthCheckWeights: map[string]int{"https/443": 3},
+	}
+	results := []bool{true, false, false}
+
+	tests := []struct {
+		policy   string
+		expected bool
+	}{
+		{"", false},
+		{"all", false},
+		{"any", true},
+		{"quorum:1", true},
+		{"quorum:2", false},
+		{"weighted:3", true},
+		{"weighted:4", false},
+	}
+	for _, tt := range tests {
+		backend.HealthCheckPolicy = tt.policy
+		assert.Equal(t, tt.expected, backend.aggregateHealthChecks(results), tt.policy)
+	}
+
+	// A backend without health checks always passes
+	assert.True(t, (&Backend{HealthCheckPolicy: "quorum:2"}).aggregateHealthChecks(nil))
+}
+
+func TestBackend_RunHealthChecks_Policy(t *testing.T) {
+	backend := &Backend{
+		Address:           "127.0.0.1",
+		HealthCheckPolicy: "any",
+		HealthChecks: []GenericHealthCheck{
+			&toggleHealthCheck{ok: true, typ: "https/443"},
+			&toggleHealthCheck{ok: false, typ: "icmp"},
+		},
+	}
+
+	backend.runHealthChecks(1, 5*time.Second)
+
+	assert.True(t, backend.Alive)
+	assert.Equal(t, []HealthCheckResult{{Type: "https/443", Passed: true}, {Type: "icmp", Error: "other"}}, backend.GetHealthCheckResults())
+	assert.Equal(t, "https/443=up,icmp=down", formatHealthCheckResults(backend.GetHealthCheckResults()))
+}
+
+func TestBackend_RunHealthChecks_SameType(t *testing.T) {
+	// Two checks of the same type count separately towards the quorum
+	backend := &Backend{
+		Address:           "127.0.0.1",
+		HealthCheckPolicy: "quorum:2",
+		HealthChecks: []GenericHealthCheck{
+			&toggleHealthCheck{ok: true, typ: "http/443"},
+			&toggleHealthCheck{ok: true, typ: "http/443"},
+			&toggleHealthCheck{ok: false, typ: "icmp"},
+		},
+	}
+
+	backend.runHealthChecks(1, 5*time.Second)
+
+	assert.True(t, backend.Alive)
+	assert.Equal(t, "http/443=up,http/443=up,icmp=down", formatHealthCheckResults(backend.GetHealthCheckResults()))
+
+	// The type is reported up only if every check of the type passed
+	backend.HealthChecks[1].(*toggleHealthCheck).ok = false
+	backend.runHealthChecks(1, 5*time.Second)
+	assert.False(t, backend.Alive)
+	assert.Equal(t, map[string]bool{"http/443": false, "icmp": false}, healthCheckTypeResults(backend.GetHealthCheckResults()))
+}
+
+func TestBackend_UnmarshalYAML_HealthCheckPolicy(t *testing.T) {
+	var backend Backend
+	yamlData := `
+address: "127.0.0.1"
+healthcheck_policy: "weighted:3"
+healthcheck_weights:
+  https/443: 3
+`
+	err := yaml.Unmarshal([]byte(yamlData), &backend)
+	assert.NoError(t, err)
+	assert.Equal(t, "weighted:3", backend.GetHealthCheckPolicy())
+	assert.Equal(t, map[string]int{"https/443": 3}, backend.GetHealthCheckWeights())
+
+	backend = Backend{}
+	assert.NoError(t, yaml.Unmarshal([]byte("address: \"127.0.0.1\"\n"), &backend))
+	assert.Equal(t, HealthCheckPolicyAll, backend.GetHealthCheckPolicy())
+
+	for _, policy := range []string{"most", "quorum", "quorum:0", "weighted:x", "any:1"} {
+		backend = Backend{}
+		err := yaml.Unmarshal([]byte("address: \"127.0.0.1\"\nhealthcheck_policy: \""+policy+"\"\n"), &backend)
+		assert.Error(t, err, policy)
+	}
+}
+
 func TestBackend_Getters(t *testing.T) {
 	b := &Backend{
 		Fqdn:           "test.example.com.",
@@ -189,6 +477,7 @@ func TestBackend_UpdateBackend(t *testing.T) {
 		Address:        "1.2.3.4", // Same address
 		Priority:       20,        // Different priority
 		Weight:         10,        // Different weight
+		Capacity:       100,       // Different capacity
 		Enable:         false,     // Different enable state
 		Description:    "new description",
 		Tags:           []string{"tag3", "tag4", "tag5"},
@@ -214,6 +503,7 @@ func TestBackend_UpdateBackend(t *testing.T) {
 	// Verify all fields were updated
 	assert.Equal(t, 20, b.Priority, "Priority should be updated")
 	assert.Equal(t, 10, b.Weight, "Weight should be updated")
+	assert.Equal(t, 100.0, b.Capacity, "Capacity should be updated")
 	assert.Equal(t, false, b.Enable, "Enable should be updated")
 	assert.Equal(t, "new description", b.Description, "Description should be updated")
 	assert.Equal(t, []string{"tag3", "tag4", "tag5"}, b.Tags, "Tags should be updated")
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -44,3 +45,20 @@ func TestPrettyPrintStatus(t *testing.T) {
 		t.Errorf("pretty print failed:\nGot:\n%s\nWant:\n%s", pretty.String(), want)
 	}
 }
+
+func TestFollowEvents(t *testing.T) {
+	stream := strings.NewReader(": keep-alive\n\n" +
+		"event: backend_status\n" +
+		`data: {"type":"backend_status","time":"2025-07-21T13:03:29Z","record":"webapp.example.com.","address":"172.16.0.10","status":"unhealthy","previous":"healthy"}` + "\n\n" +
+		"event: config_reload\n" +
+		`data: {"type":"config_reload","time":"2025-07-21T13:04:00Z","zone":"example.com.","status":"failure","reason":"invalid YAML"}` + "\n\n")
+	var out bytes.Buffer
+	if err := followEvents(stream, &out, false); err != nil {
+		t.Fatalf("followEvents failed: %v", err)
+	}
+	want := "2025-07-21T13:03:29Z backend_status webapp.example.com. 172.16.0.10 healthy -> unhealthy\n" +
+		"2025-07-21T13:04:00Z config_reload example.com. failure (invalid YAML)\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\nGot:\n%s\nWant:\n%s", out.String(), want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -20,11 +21,12 @@ const defaultCorefilePath = "/coredns/Corefile"
 
 // Config holds parsed Corefile values
 type Config struct {
-	User string
-	Pass string
-	TLS  bool
-	Addr string
-	Port string
+	User  string
+	Pass  string
+	Token string
+	TLS   bool
+	Addr  string
+	Port  string
 }
 
 // Parse Corefile for auth and API info
@@ -33,7 +35,7 @@ func parseCorefile() Config {
 	if path == "" {
 		path = defaultCorefilePath
 	}
-	cfg := Config{Addr: "127.0.0.1", Port: "8080"}
+	cfg := Config{Addr: "127.0.0.1", Port: "8080", Token: os.Getenv("GSLB_API_TOKEN")}
 	f, err := os.Open(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[gslbctl] Warning: cannot open Corefile: %v\n", err)
@@ -86,8 +88,12 @@ func apiURL(cfg Config) string {
 	return fmt.Sprintf("%s://%s:%s", proto, cfg.Addr, cfg.Port)
 }
 
-// Add basic auth header if needed
+// Add the API token or basic auth header if needed
 func addAuth(req *http.Request, cfg Config) {
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+		return
+	}
 	if cfg.User != "" && cfg.Pass != "" {
 		auth := cfg.User + ":" + cfg.Pass
 		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
@@ -107,6 +113,8 @@ func main() {
 		backendsCmd(os.Args[2:], api, cfg)
 	case "status":
 		statusCmd(api, cfg)
+	case "events":
+		eventsCmd(os.Args[2:], api, cfg)
 	default:
 		usage()
 		os.Exit(1)
@@ -120,6 +128,7 @@ Commands:
   backends enable   [--tags tag1,tag2] [--address addr] [--location loc]
   backends disable  [--tags tag1,tag2] [--address addr] [--location loc]
   status
+  events            [--type type1,type2] [--json]
 `)
 }
 
@@ -275,3 +284,98 @@ func statusCmd(api string, cfg Config) {
 		fmt.Println(pretty.String())
 	}
 }
+
+// event is a state change received from the event stream.
+type event struct {
+	Type     string `json:"type"`
+	Time     string `json:"time"`
+	Zone     string `json:"zone"`
+	Record   string `json:"record"`
+	Address  string `json:"address"`
+	Status   string `json:"status"`
+	Previous string `json:"previous"`
+	Reason   string `json:"reason"`
+}
+
+func eventsCmd(args []string, api string, cfg Config) {
+	fs := flag.NewFlagSet("events", flag.ExitOnError)
+	types := fs.String("type", "", "Comma-separated list of event types")
+	raw := fs.Bool("json", false, "Print the events as JSON")
+	fs.Parse(args)
+
+	endpoint := api + "/api/events"
+	if *types != "" {
+		endpoint += "?type=" + url.QueryEscape(*types)
+	}
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "API error: %v\n", err)
+		os.Exit(2)
+	}
+	addAuth(req, cfg)
+	req.Header.Set("Accept", "text/event-stream")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "API error: %v\n", err)
+		os.Exit(2)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "API error: %s %s\n", resp.Status, strings.TrimSpace(string(data)))
+		os.Exit(2)
+	}
+	if err := followEvents(resp.Body, os.Stdout, *raw); err != nil {
+		fmt.Fprintf(os.Stderr, "API error: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// followEvents reads a Server-Sent Events stream and prints one line per event until the stream ends.
+func followEvents(stream io.Reader, out io.Writer, raw bool) error {
+	scanner := bufio.NewScanner(stream)
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+			continue
+		}
+		if line != "" || len(data) == 0 {
+			continue
+		}
+		payload := strings.Join(data, "\n")
+		data = nil
+		if raw {
+			fmt.Fprintln(out, payload)
+			continue
+		}
+		var e event
+		if err := json.Unmarshal([]byte(payload), &e); err != nil {
+			fmt.Fprintln(out, payload)
+			continue
+		}
+		fmt.Fprintln(out, formatEvent(e))
+	}
+	return scanner.Err()
+}
+
+// formatEvent returns a one-line summary of an event.
+func formatEvent(e event) string {
+	parts := []string{e.Time, e.Type}
+	for _, s := range []string{e.Zone, e.Record, e.Address} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if e.Previous != "" {
+		parts = append(parts, e.Previous+" -> "+e.Status)
+	} else {
+		parts = append(parts, e.Status)
+	}
+	if e.Reason != "" {
+		parts = append(parts, "("+e.Reason+")")
+	}
+	return strings.Join(parts, " ")
+}
@@ -3,6 +3,8 @@ package gslb
 import (
 	"context"
 	"net"
+
+	"github.com/miekg/dns"
 )
 
 type clientCtxKey struct{}
@@ -10,6 +12,7 @@ type clientCtxKey struct{}
 type ClientInfo struct {
 	IP        net.IP
 	PrefixLen uint8
+	Scope     uint8 // ECS scope prefix length of the answer, 0 when it does not depend on the client
 }
 
 func WithClientInfo(ctx context.Context, ip net.IP, prefix uint8) context.Context {
@@ -23,3 +26,46 @@ func GetClientInfo(ctx context.Context) *ClientInfo {
 	}
 	return nil
 }
+
+// answerWriter returns the writer for an answer of the plugin itself, echoing the client subnet of the query
+// with the scope of the answer so that resolvers cache it for the right clients. Queries passed to the next
+// plugin keep the original writer.
+func (g *GSLB) answerWriter(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) dns.ResponseWriter {
+	if ecs := g.clientSubnet(r); ecs != nil {
+		return &ecsWriter{ResponseWriter: w, ecs: ecs, client: GetClientInfo(ctx)}
+	}
+	return w
+}
+
+// ecsWriter echoes the EDNS Client Subnet option of the query in the response, with the scope
+// prefix length of the answer (RFC 7871), so that resolvers cache it only for the clients it applies to.
+type ecsWriter struct {
+	dns.ResponseWriter
+	ecs    *dns.EDNS0_SUBNET
+	client *ClientInfo
+}
+
+func (w *ecsWriter) WriteMsg(m *dns.Msg) error {
+	opt := m.IsEdns0()
+	if opt == nil {
+		// The UDP size and DO bit are set from the query by CoreDNS
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		opt = m.IsEdns0()
+	}
+	for _, option := range opt.Option {
+		// Answered by another plugin that set it already
+		if _, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return w.ResponseWriter.WriteMsg(m)
+		}
+	}
+	// The scope is never longer than the source prefix the answer was chosen with
+	scope := min(w.client.Scope, w.ecs.SourceNetmask)
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        w.ecs.Family,
+		SourceNetmask: w.ecs.SourceNetmask,
+		SourceScope:   scope,
+		Address:       w.ecs.Address,
+	})
+	return w.ResponseWriter.WriteMsg(m)
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,3 +23,27 @@ func TestWithAndGetClientInfo(t *testing.T) {
 	empty := GetClientInfo(context.Background())
 	assert.Nil(t, empty)
 }
+
+func TestECSWriter(t *testing.T) {
+	query := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 2, SourceNetmask: 56, Address: net.ParseIP("2001:db8::")}
+	rec := &mockResponseWriter{}
+	w := &ecsWriter{ResponseWriter: rec, ecs: query, client: &ClientInfo{Scope: 64}}
+
+	// An OPT record is added to carry the option, with the scope clamped to the source prefix
+	m := new(dns.Msg)
+	assert.NoError(t, w.WriteMsg(m))
+	ecs := responseSubnet(rec.msg)
+	assert.NotNil(t, ecs)
+	assert.Equal(t, uint16(2), ecs.Family)
+	assert.Equal(t, uint8(56), ecs.SourceNetmask)
+	assert.Equal(t, uint8(56), ecs.SourceScope)
+
+	// An option set by another plugin is kept
+	m = new(dns.Msg)
+	m.SetEdns0(dns.DefaultMsgSize, false)
+	other := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 2, SourceNetmask: 56, SourceScope: 48, Address: net.ParseIP("2001:db8::")}
+	m.IsEdns0().Option = append(m.IsEdns0().Option, other)
+	assert.NoError(t, w.WriteMsg(m))
+	assert.Len(t, rec.msg.IsEdns0().Option, 1)
+	assert.Same(t, other, responseSubnet(rec.msg))
+}
@@ -0,0 +1,161 @@
+package gslb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types published on the event bus.
+const (
+	EventBackendStatus = "backend_status" // A backend became healthy or unhealthy
+	EventRecordStatus  = "record_status"  // A record became healthy or unhealthy
+	EventConfigReload  = "config_reload"  // A zone file was reloaded
+	EventBackendEnable = "backend_enable" // A backend was enabled or disabled through the API
+)
+
+// eventSubscriberBuffer is the number of events kept for a subscriber that does not keep up.
+const eventSubscriberBuffer = 64
+
+// eventKeepAlive is how often a comment is sent on idle event streams.
+const eventKeepAlive = 15 * time.Second
+
+// Event is a state change published on the event bus.
+type Event struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Zone     string    `json:"zone,omitempty"`
+	Record   string    `json:"record,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	Status   string    `json:"status"`             // healthy, unhealthy, enabled, disabled, success or failure
+	Previous string    `json:"previous,omitempty"` // Status before the change, if known
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// EventBus fans out events to its subscribers. Publishing never blocks: a subscriber whose
+// buffer is full misses the event.
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// events is the event bus of the plugin, shared like the metrics.
+var events = &EventBus{}
+
+// Subscribe returns a channel receiving the events published from now on.
+func (b *EventBus) Subscribe() chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan Event]struct{})
+	}
+	ch := make(chan Event, eventSubscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe stops the delivery of events to a channel returned by Subscribe.
+func (b *EventBus) Unsubscribe(ch chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// Publish sends an event to all subscribers.
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Warningf("event subscriber too slow, dropping %s event", event.Type)
+		}
+	}
+}
+
+// healthStatus returns the status string of a health state.
+func healthStatus(healthy bool) string {
+	if healthy {
+		return statusHealthy
+	}
+	return statusUnhealthy
+}
+
+// enableStatus returns the status string of an enable value.
+func enableStatus(enable bool) string {
+	if enable {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// handleEvents returns a handler streaming the events as Server-Sent Events.
+// The stream can be restricted to some event types with ?type=backend_status,record_status.
+func (g *GSLB) handleEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := g.checkAuth(w, r)
+		if !ok {
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET is supported."})
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Streaming not supported"})
+			return
+		}
+		types := make(map[string]bool)
+		if t := r.URL.Query().Get("type"); t != "" {
+			for _, typ := range strings.Split(t, ",") {
+				types[strings.TrimSpace(typ)] = true
+			}
+		}
+
+		// The stream outlives the write timeout of the API server
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		ch := events.Subscribe()
+		defer events.Unsubscribe(ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			case event := <-ch:
+				if (len(types) > 0 && !types[event.Type]) || !g.eventAllowed(cred, event) {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+				flusher.Flush()
+			}
+		}
+	}
+}
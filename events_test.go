@@ -0,0 +1,136 @@
+package gslb
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiveEvent returns the next event of a record, skipping the events of other tests' records.
+func receiveEvent(t *testing.T, ch chan Event, record string) Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Record == record {
+				return e
+			}
+		case <-timeout:
+			t.Fatal("no event received")
+			return Event{}
+		}
+	}
+}
+
+func TestEventBus_PublishSubscribe(t *testing.T) {
+	bus := &EventBus{}
+	ch := bus.Subscribe()
+	bus.Publish(Event{Type: EventConfigReload, Zone: "example.com.", Status: "success"})
+	e := <-ch
+	assert.Equal(t, EventConfigReload, e.Type)
+	assert.False(t, e.Time.IsZero())
+
+	// A full subscriber does not block the publisher
+	for i := 0; i < eventSubscriberBuffer+1; i++ {
+		bus.Publish(Event{Type: EventConfigReload})
+	}
+	assert.Len(t, ch, eventSubscriberBuffer)
+
+	bus.Unsubscribe(ch)
+	bus.Publish(Event{Type: EventConfigReload})
+	assert.Len(t, ch, eventSubscriberBuffer)
+}
+
+func TestEvents_BackendAndRecordStatus(t *testing.T) {
+	ch := events.Subscribe()
+	defer events.Unsubscribe(ch)
+
+	hc := &toggleHealthCheck{ok: true, typ: "toggle"}
+	backend := &Backend{Fqdn: "events.example.com.", Address: "10.0.0.1", Enable: true, HealthChecks: []GenericHealthCheck{hc}}
+	record := &Record{Fqdn: "events.example.com.", Backends: []BackendInterface{backend}}
+	backend.runHealthChecks(0, time.Second)
+	record.updateRecordHealthStatus()
+	e := receiveEvent(t, ch, "events.example.com.")
+	assert.Equal(t, "healthy", e.Status)
+	assert.Equal(t, "unhealthy", e.Previous)
+
+	hc.ok = false
+	backend.runHealthChecks(0, time.Second)
+	record.updateRecordHealthStatus()
+
+	e = receiveEvent(t, ch, "events.example.com.")
+	assert.Equal(t, Event{Type: EventBackendStatus, Time: e.Time, Record: "events.example.com.", Address: "10.0.0.1", Status: "unhealthy", Previous: "healthy"}, e)
+	e = receiveEvent(t, ch, "events.example.com.")
+	assert.Equal(t, Event{Type: EventRecordStatus, Time: e.Time, Record: "events.example.com.", Status: "unhealthy", Previous: "healthy"}, e)
+}
+
+func TestEvents_BackendEnable(t *testing.T) {
+	ch := events.Subscribe()
+	defer events.Unsubscribe(ch)
+
+	backend := &Backend{Fqdn: "events.example.com.", Address: "10.0.0.1", Enable: true}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"events.example.com.": {Fqdn: "events.example.com.", Backends: []BackendInterface{backend}}},
+	}}
+	g.setOverride("events.example.com.", backend, false, "drain", nil)
+	e := receiveEvent(t, ch, "events.example.com.")
+	assert.Equal(t, EventBackendEnable, e.Type)
+	assert.Equal(t, "disabled", e.Status)
+	assert.Equal(t, "drain", e.Reason)
+
+	// Disabling again publishes nothing, the next event is the clear
+	g.setOverride("events.example.com.", backend, false, "drain", nil)
+	g.clearOverride("events.example.com.", "10.0.0.1")
+	e = receiveEvent(t, ch, "events.example.com.")
+	assert.Equal(t, "enabled", e.Status)
+	assert.Equal(t, "disabled", e.Previous)
+}
+
+func TestAPIEventsEndpoint(t *testing.T) {
+	g := &GSLB{APIBasicUser: "admin", APIBasicPass: "secret"}
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Authentication is required
+	resp, err := http.Get(ts.URL + "/api/events")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/events?type=config_reload", nil)
+	req.SetBasicAuth("admin", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Wait for the subscription, then publish a filtered out event and a streamed one
+	assert.Eventually(t, func() bool {
+		events.mutex.Lock()
+		defer events.mutex.Unlock()
+		return len(events.subscribers) > 0
+	}, time.Second, 10*time.Millisecond)
+	events.Publish(Event{Type: EventBackendStatus, Record: "app.example.com.", Status: "unhealthy"})
+	events.Publish(Event{Type: EventConfigReload, Zone: "example.com.", Status: "success"})
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: config_reload\n", line)
+	line, err = reader.ReadString('\n')
+	assert.NoError(t, err)
+	var e Event
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+	assert.Equal(t, "example.com.", e.Zone)
+	assert.Equal(t, "success", e.Status)
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"os"
@@ -15,6 +17,7 @@ import (
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/miekg/dns"
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 	"gopkg.in/yaml.v3"
 )
 
@@ -40,15 +43,50 @@ type GSLB struct {
 	GeoIPCountryDB            *geoip2.Reader // Loaded MaxMind DB (country)
 	GeoIPCityDB               *geoip2.Reader // Loaded MaxMind DB (city)
 	GeoIPASNDB                *geoip2.Reader // Loaded MaxMind DB (ASN)
-	APIEnable                 bool           // Enable/disable API HTTP server
-	APICertPath               string         // TLS certificate path for API
-	APIKeyPath                string         // TLS key path for API
-	APIListenAddr             string         // API listen address (default 0.0.0.0)
-	APIListenPort             string         // API listen port (default 8080)
-	APIBasicUser              string         // HTTP Basic Auth username (optional)
-	APIBasicPass              string         // HTTP Basic Auth password (optional)
+	// The same MaxMind DBs, for the network of the client address announced as ECS scope
+	GeoIPCountryNetworks *maxminddb.Reader
+	GeoIPCityNetworks    *maxminddb.Reader
+	GeoIPASNNetworks     *maxminddb.Reader
+	APIEnable            bool             // Enable/disable API HTTP server
+	APICertPath          string           // TLS certificate path for API
+	APIKeyPath           string           // TLS key path for API
+	APIListenAddr        string           // API listen address (default 0.0.0.0)
+	APIListenPort        string           // API listen port (default 8080)
+	APIBasicUser         string           // HTTP Basic Auth username (optional)
+	APIBasicPass         string           // HTTP Basic Auth password (optional)
+	APITokens            []*APICredential // Bearer tokens allowed to use the API
+	APIClients           []*APICredential // Client certificates allowed to use the API
+	APIClientCA          string           // CA bundle verifying the API client certificates
+	APIReadTimeout       string           // Maximum duration to read an API request (default 10s)
+	APIWriteTimeout      string           // Maximum duration to write an API response (default 30s)
+	APIHTTP2             bool             // Serve the API over HTTP/2 too, with TLS or cleartext (h2c)
 	// DisableTXT disables TXT record resolution if set to true
 	DisableTXT bool
+	// Authoritative makes the plugin answer SOA/NS, NXDOMAIN and NODATA itself for its zones
+	Authoritative bool
+	Nameservers   []string // NS records of the zones (default ns1.<zone>)
+	NegativeTTL   int      // SOA minimum and negative caching TTL in seconds
+	ZoneSerial    sync.Map // key: zone (string), value: SOA serial (uint32)
+	// StateFile persists the backends health state across restarts if set
+	StateFile     string
+	StateInterval string // How often the state file is written
+	StateMaxAge   string // Maximum age of a persisted state to be restored
+	// Overrides are runtime enable/disable of backends set through the API, keyed by record and address
+	Overrides      map[string]*BackendOverride
+	Maintenance    map[string][]MaintenanceWindow // Maintenance windows created through the API, same keys
+	OverridesFile  string                         // Path where the overrides and maintenance windows are persisted
+	overridesMutex sync.Mutex
+	zoneConfigs    map[string]*zoneConfig // Raw content of the zone files, changed by the records API
+	zoneWrites     sync.Map               // Content last written to each zone file by the records API
+	// Notifiers send the health state changes to webhooks
+	Notifiers []*Notifier
+	// AuditFile is the JSON lines file the API changes and config reloads are appended to
+	AuditFile  string
+	auditTrail auditTrail
+	apiServer  *http.Server // Running API server, nil when stopped
+	apiMutex   sync.Mutex
+	zoneErrors map[string]string // Error of the last load of each zone file that failed
+	ready      atomic.Bool       // Set once every record completed its first health pass
 }
 
 func (g *GSLB) Name() string { return "gslb" }
@@ -189,6 +227,14 @@ func (g *GSLB) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
 	}
 
+	// Answer SOA, NS and negative responses for the zones when no other plugin serves them
+	if g.Authoritative {
+		w = &authoritativeWriter{ResponseWriter: w}
+		if rcode, handled, err := g.serveAuthority(w, r, domain, q.Qtype); handled {
+			return rcode, err
+		}
+	}
+
 	// Determine the client IP and prefix length (ECS or RemoteAddr fallback)
 	clientIP, clientPrefixLen := g.extractClientIP(w, r)
 	if clientIP == nil {
@@ -206,6 +252,10 @@ func (g *GSLB) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		return g.handleIPRecord(ctx, w, r, domain, dns.TypeA)
 	case dns.TypeAAAA:
 		return g.handleIPRecord(ctx, w, r, domain, dns.TypeAAAA)
+	case dns.TypeSRV:
+		return g.handleSRVRecord(ctx, w, r, domain)
+	case dns.TypeHTTPS, dns.TypeSVCB:
+		return g.handleSVCBRecord(ctx, w, r, domain, q.Qtype)
 	case dns.TypeTXT:
 		if g.DisableTXT {
 			return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
@@ -216,35 +266,14 @@ func (g *GSLB) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 	}
 }
 
-func (g *GSLB) ServeAPI() {
-	mux := http.NewServeMux()
-	g.RegisterAPIHandlers(mux)
-	listenAddr := g.APIListenAddr + ":" + g.APIListenPort
-	if g.APICertPath != "" && g.APIKeyPath != "" {
-		go func() {
-			_ = http.ListenAndServeTLS(listenAddr, g.APICertPath, g.APIKeyPath, mux)
-		}()
-	} else {
-		go func() {
-			_ = http.ListenAndServe(listenAddr, mux)
-		}()
-	}
-}
-
 func (g *GSLB) extractClientIP(w dns.ResponseWriter, r *dns.Msg) (net.IP, uint8) {
 	var clientIP net.IP
 	var prefixLen uint8 = 32 // Default for IPv4
 
 	// Check for EDNS options
-	if g.UseEDNSCSubnet {
-		if o := r.IsEdns0(); o != nil {
-			for _, option := range o.Option {
-				if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
-					log.Debugf("ECS Detected: IP=%s, PrefixLength=%d", ecs.Address, ecs.SourceNetmask)
-					return ecs.Address, ecs.SourceNetmask
-				}
-			}
-		}
+	if ecs := g.clientSubnet(r); ecs != nil {
+		log.Debugf("ECS Detected: IP=%s, PrefixLength=%d", ecs.Address, ecs.SourceNetmask)
+		return ecs.Address, ecs.SourceNetmask
 	}
 
 	// Fallback to remote address if ECS is not present
@@ -267,6 +296,21 @@ func (g *GSLB) extractClientIP(w dns.ResponseWriter, r *dns.Msg) (net.IP, uint8)
 	return clientIP, prefixLen
 }
 
+// clientSubnet returns the EDNS Client Subnet option of the query, or nil if absent or use_edns_csubnet is off.
+func (g *GSLB) clientSubnet(r *dns.Msg) *dns.EDNS0_SUBNET {
+	if !g.UseEDNSCSubnet {
+		return nil
+	}
+	if o := r.IsEdns0(); o != nil {
+		for _, option := range o.Option {
+			if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
+				return ecs
+			}
+		}
+	}
+	return nil
+}
+
 func (g *GSLB) isAuthoritative(domain string) bool {
 	domainNorm := strings.ToLower(strings.TrimSuffix(domain, ".")) + "."
 	for authZone := range g.Zones {
@@ -287,25 +331,49 @@ func (g *GSLB) handleIPRecord(ctx context.Context, w dns.ResponseWriter, r *dns.
 		log.Error("No client info in context")
 		return dns.RcodeServerFailure, nil
 	}
+	answer := g.answerWriter(ctx, w, r)
+	if !record.hasBackendOfType(recordType) {
+		// Nothing is down, the record has no address of this family
+		return g.sendNoData(ctx, w, r, domain)
+	}
 	start := time.Now()
-	ip, err := g.pickResponse(domain, recordType, ci.IP)
+	ip, scope, err := g.pickResponse(domain, recordType, ci)
 	if err != nil {
 		log.Debugf("[%s] no backend available for type %d: %v", domain, recordType, err)
 
-		// Fallback: get all IP addresses
-		ipAddresses, err := g.pickAllAddresses(domain, recordType)
+		// Fallback: apply the record fallback policy
+		ipAddresses, err := g.pickFallbackAddresses(record, recordType)
 		if err != nil {
 			log.Debugf("Error retrieving backends for domain %s: %v", domain, err)
 			ObserveRecordResolutionDuration(domain, "fail", time.Since(start).Seconds())
-			return dns.RcodeServerFailure, nil
+			return g.sendNoFallback(answer, r, record, domain)
 		}
 
 		ObserveRecordResolutionDuration(domain, "fail", time.Since(start).Seconds())
-		return g.sendAddressRecordResponse(w, r, domain, ipAddresses, record.RecordTTL, recordType)
+		return g.sendAddressRecordResponse(answer, r, domain, ipAddresses, record.GetTTL(), recordType)
 	}
 
 	ObserveRecordResolutionDuration(domain, "success", time.Since(start).Seconds())
-	return g.sendAddressRecordResponse(w, r, domain, ip, record.RecordTTL, recordType)
+	ci.Scope = scope
+	return g.sendAddressRecordResponse(answer, r, domain, ip, record.GetTTL(), recordType)
+}
+
+// sendNoFallback answers a query that neither the backends nor the fallback policy of the record can answer:
+// SERVFAIL, or NODATA when authoritative unless the fallback policy is none.
+func (g *GSLB) sendNoFallback(w dns.ResponseWriter, r *dns.Msg, record *Record, domain string) (int, error) {
+	if g.Authoritative && record.GetFallback() != FallbackNone {
+		return g.sendNegativeResponse(w, r, g.findZone(domain), dns.RcodeSuccess)
+	}
+	return dns.RcodeServerFailure, nil
+}
+
+// sendNoData answers a query for a record without data of the queried type: NODATA when authoritative,
+// or else the next plugin answers.
+func (g *GSLB) sendNoData(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, domain string) (int, error) {
+	if g.Authoritative {
+		return g.sendNegativeResponse(g.answerWriter(ctx, w, r), r, g.findZone(domain), dns.RcodeSuccess)
+	}
+	return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
 }
 
 func (g *GSLB) handleTXTRecord(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, domain string) (int, error) {
@@ -315,8 +383,13 @@ func (g *GSLB) handleTXTRecord(ctx context.Context, w dns.ResponseWriter, r *dns
 		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
 	}
 
-	// Prepare a list to store the backend summaries
-	var summaries []string
+	// Prepare a list to store the record and backend summaries
+	ttl := record.GetTTL()
+	mode := record.Mode
+	if len(record.Policy) > 0 {
+		mode = strings.Join(record.Policy, " > ")
+	}
+	summaries := [][]string{{fmt.Sprintf("Record: %s | Mode: %s | Fallback: %s | TTL: %d", record.Fqdn, mode, record.GetFallback(), ttl)}}
 	for _, backend := range record.Backends {
 		// Determine the backend's health status
 		status := "unhealthy"
@@ -347,8 +420,17 @@ func (g *GSLB) handleTXTRecord(ctx context.Context, w dns.ResponseWriter, r *dns
 			"Backend: %s | Priority: %d | Status: %s | Enabled: %v | LastHealthcheck: %s | ResponseTime: %s",
 			backend.GetAddress(), backend.GetPriority(), status, enabled, lastHealthcheck, responseTime,
 		)
+		// Healthcheck results go in a second string to stay below the 255 bytes limit
+		healthchecks := fmt.Sprintf(
+			"HealthcheckPolicy: %s | Healthchecks: %s",
+			backend.GetHealthCheckPolicy(), formatHealthCheckResults(backend.GetHealthCheckResults()),
+		)
+		strs := []string{summary, healthchecks}
+		if windows := backend.GetMaintenanceWindows(); len(windows) > 0 {
+			strs = append(strs, formatMaintenance(windows, time.Now()))
+		}
 		// Add the summary to the list
-		summaries = append(summaries, summary)
+		summaries = append(summaries, strs)
 	}
 
 	// Create the DNS response message
@@ -363,16 +445,16 @@ func (g *GSLB) handleTXTRecord(ctx context.Context, w dns.ResponseWriter, r *dns
 				Name:   domain,
 				Rrtype: dns.TypeTXT,
 				Class:  dns.ClassINET,
-				Ttl:    uint32(record.RecordTTL),
+				Ttl:    uint32(ttl),
 			},
-			Txt: []string{summary},
+			Txt: summary,
 		}
 		// Append the TXT record to the response
 		response.Answer = append(response.Answer, txt)
 	}
 
 	// Send the DNS response with the multiple TXT records
-	if err := w.WriteMsg(response); err != nil {
+	if err := g.answerWriter(ctx, w, r).WriteMsg(response); err != nil {
 		log.Error("Failed to write DNS TXT response: ", err)
 		return dns.RcodeServerFailure, err
 	}
@@ -381,6 +463,163 @@ func (g *GSLB) handleTXTRecord(ctx context.Context, w dns.ResponseWriter, r *dns
 	return dns.RcodeSuccess, nil
 }
 
+func (g *GSLB) handleSRVRecord(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, domain string) (int, error) {
+	record, _ := g.findRecord(domain)
+	if record == nil {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+	if !record.hasBackendOfType(dns.TypeSRV) {
+		// Nothing is down, the record has no backend to announce
+		return g.sendNoData(ctx, w, r, domain)
+	}
+	start := time.Now()
+	backends, err := g.pickSRVBackends(record)
+	selected := err == nil
+	if err != nil {
+		log.Debugf("[%s] no backend available for type SRV: %v", domain, err)
+
+		// Fallback: apply the record fallback policy
+		backends, err = g.pickFallbackSRVBackends(record)
+		if err != nil {
+			log.Debugf("Error retrieving SRV backends for domain %s: %v", domain, err)
+			ObserveRecordResolutionDuration(domain, "fail", time.Since(start).Seconds())
+			return g.sendNoFallback(g.answerWriter(ctx, w, r), r, record, domain)
+		}
+		ObserveRecordResolutionDuration(domain, "fail", time.Since(start).Seconds())
+	} else {
+		ObserveRecordResolutionDuration(domain, "success", time.Since(start).Seconds())
+	}
+
+	response := new(dns.Msg)
+	response.SetReply(r)
+	ttl := uint32(record.GetTTL())
+	for _, backend := range backends {
+		target := srvTarget(backend)
+		response.Answer = append(response.Answer, &dns.SRV{
+			Hdr: dns.RR_Header{
+				Name:   domain,
+				Rrtype: dns.TypeSRV,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			Priority: uint16(backend.GetPriority()),
+			Weight:   uint16(backend.GetWeight()),
+			Port:     uint16(backend.GetPort()),
+			Target:   target,
+		})
+
+		// Glue the backend address to its target when the target is only an alias for an IP
+		ip := net.ParseIP(backend.GetAddress())
+		switch {
+		case ip == nil:
+			continue
+		case ip.To4() != nil:
+			response.Extra = append(response.Extra, &dns.A{
+				Hdr: dns.RR_Header{Name: target, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   ip,
+			})
+		default:
+			response.Extra = append(response.Extra, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: target, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+				AAAA: ip,
+			})
+		}
+	}
+
+	if err := g.answerWriter(ctx, w, r).WriteMsg(response); err != nil {
+		log.Error("Failed to write DNS SRV response: ", err)
+		IncRecordResolutions(domain, "fail")
+		return dns.RcodeServerFailure, err
+	}
+	if selected {
+		for _, backend := range backends {
+			IncBackendSelected(record.Fqdn, backend.GetAddress())
+		}
+	}
+	IncRecordResolutions(domain, "success")
+	return dns.RcodeSuccess, nil
+}
+
+// pickSRVBackends returns every healthy backend that can be announced in an SRV answer, sorted by priority.
+// Unlike A/AAAA answers all priorities are kept, since SRV clients perform the failover themselves.
+func (g *GSLB) pickSRVBackends(record *Record) ([]BackendInterface, error) {
+	var healthy []BackendInterface
+	for _, backend := range record.Backends {
+		if backend.IsHealthy() && backendMatchesType(backend, dns.TypeSRV) {
+			healthy = append(healthy, backend)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, fmt.Errorf("no healthy backends with port and target for type SRV")
+	}
+	sort.SliceStable(healthy, func(i, j int) bool {
+		return healthy[i].GetPriority() < healthy[j].GetPriority()
+	})
+	return healthy, nil
+}
+
+// pickFallbackSRVBackends returns the backends to announce when no backend is healthy, according to the record
+// fallback policy. The static and cname policies give no port to announce, so they have no SRV answer.
+func (g *GSLB) pickFallbackSRVBackends(record *Record) ([]BackendInterface, error) {
+	policy := record.GetFallback()
+	var backends []BackendInterface
+	switch policy {
+	case FallbackNone:
+		IncRecordFallback(record.Fqdn, policy)
+		return nil, fmt.Errorf("fallback disabled for domain: %s", record.Fqdn)
+	case FallbackLastHealthy:
+		lastHealthy := record.getLastHealthy()
+		if len(lastHealthy) == 0 {
+			// Never been healthy since startup: nothing better than every enabled backend
+			policy = FallbackAll
+			break
+		}
+		for _, backend := range lastHealthy {
+			if backendMatchesType(backend, dns.TypeSRV) {
+				backends = append(backends, backend)
+			}
+		}
+		sort.SliceStable(backends, func(i, j int) bool {
+			return backends[i].GetPriority() < backends[j].GetPriority()
+		})
+	}
+
+	if policy == FallbackAll {
+		backends = g.pickAllSRVBackends(record)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no fallback SRV backend for domain %s with policy %s", record.Fqdn, policy)
+	}
+	IncRecordFallback(record.Fqdn, policy)
+	return backends, nil
+}
+
+// pickAllSRVBackends returns every enabled backend that can be announced in an SRV answer, sorted by priority.
+func (g *GSLB) pickAllSRVBackends(record *Record) []BackendInterface {
+	var enabled []BackendInterface
+	for _, backend := range record.Backends {
+		if backend.IsEnabled() && !backend.InMaintenance() && backendMatchesType(backend, dns.TypeSRV) {
+			enabled = append(enabled, backend)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].GetPriority() < enabled[j].GetPriority()
+	})
+	return enabled
+}
+
+// srvTarget returns the SRV target of a backend: its explicit target, or its address for CNAME backends.
+// IP backends without a target cannot be announced in SRV answers.
+func srvTarget(backend BackendInterface) string {
+	if backend.GetTarget() != "" {
+		return dns.Fqdn(backend.GetTarget())
+	}
+	if backend.IsCNAME() {
+		return dns.Fqdn(backend.GetAddress())
+	}
+	return ""
+}
+
 func (g *GSLB) pickAllAddresses(domain string, recordType uint16) ([]string, error) {
 	record, _ := g.findRecord(domain)
 	if record == nil {
@@ -389,12 +628,8 @@ func (g *GSLB) pickAllAddresses(domain string, recordType uint16) ([]string, err
 
 	var ipAddresses []string
 	for _, backend := range record.Backends {
-		if backend.IsEnabled() {
-			ip := backend.GetAddress()
-			if (recordType == dns.TypeA && net.ParseIP(ip).To4() != nil) ||
-				(recordType == dns.TypeAAAA && net.ParseIP(ip).To16() != nil && net.ParseIP(ip).To4() == nil) {
-				ipAddresses = append(ipAddresses, ip)
-			}
+		if backend.IsEnabled() && !backend.InMaintenance() && backendMatchesType(backend, recordType) {
+			ipAddresses = append(ipAddresses, backend.GetAddress())
 		}
 	}
 
@@ -405,36 +640,114 @@ func (g *GSLB) pickAllAddresses(domain string, recordType uint16) ([]string, err
 	return ipAddresses, nil
 }
 
-func (g *GSLB) pickResponse(domain string, recordType uint16, clientIP net.IP) ([]string, error) {
-	record, _ := g.findRecord(domain)
+// pickFallbackAddresses returns the addresses to answer when no backend is healthy, according to the record fallback policy.
+func (g *GSLB) pickFallbackAddresses(record *Record, recordType uint16) ([]string, error) {
+	addresses, policy, err := g.fallbackAddresses(record, recordType)
+	if err == nil || policy == FallbackNone {
+		IncRecordFallback(record.Fqdn, policy)
+	}
+	return addresses, err
+}
+
+// fallbackAddresses returns the addresses of the record fallback policy for the given type, and the policy applied.
+func (g *GSLB) fallbackAddresses(record *Record, recordType uint16) ([]string, string, error) {
+	policy := record.GetFallback()
+	var addresses []string
+	switch policy {
+	case FallbackNone:
+		return nil, policy, fmt.Errorf("fallback disabled for domain: %s", record.Fqdn)
+	case FallbackStatic:
+		for _, addr := range record.FallbackAddresses {
+			ip := net.ParseIP(addr)
+			if (recordType == dns.TypeA && ip.To4() != nil) || (recordType == dns.TypeAAAA && ip != nil && ip.To4() == nil) {
+				addresses = append(addresses, addr)
+			}
+		}
+	case FallbackCNAME:
+		addresses = []string{record.FallbackCNAME}
+	case FallbackLastHealthy:
+		lastHealthy := record.getLastHealthy()
+		if len(lastHealthy) == 0 {
+			// Never been healthy since startup: nothing better than every enabled backend
+			policy = FallbackAll
+			break
+		}
+		for _, backend := range lastHealthy {
+			if backendMatchesType(backend, recordType) {
+				addresses = append(addresses, backend.GetAddress())
+			}
+		}
+	}
+
+	if policy == FallbackAll {
+		var err error
+		addresses, err = g.pickAllAddresses(record.Fqdn, recordType)
+		if err != nil {
+			return nil, policy, err
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, policy, fmt.Errorf("no fallback address for domain %s with policy %s", record.Fqdn, policy)
+	}
+	return addresses, policy, nil
+}
+
+// pickResponse returns the addresses answered for a domain to a client, and the ECS scope prefix length of
+// the answer: the length of the client network the answer was chosen for, 0 if it does not depend on the client.
+func (g *GSLB) pickResponse(domain string, recordType uint16, client *ClientInfo) ([]string, uint8, error) {
+	return g.selectResponse(&Selection{GSLB: g, Domain: domain, RecordType: recordType, Client: client})
+}
+
+// previewResponse returns the addresses pickResponse would answer, without counting the selected backends
+// nor advancing the round robin.
+func (g *GSLB) previewResponse(domain string, recordType uint16, client *ClientInfo) ([]string, uint8, error) {
+	return g.selectResponse(&Selection{GSLB: g, Domain: domain, RecordType: recordType, Client: client, Preview: true})
+}
+
+// selectResponse runs a selection through the routing policy of its record, or else of its mode.
+func (g *GSLB) selectResponse(s *Selection) ([]string, uint8, error) {
+	record, _ := g.findRecord(s.Domain)
 	if record == nil {
-		return nil, fmt.Errorf("domain not found: %s", domain)
+		return nil, 0, fmt.Errorf("domain not found: %s", s.Domain)
 	}
+	s.Record = record
 
-	switch record.Mode {
-	case "failover":
-		return g.pickBackendWithFailover(record, recordType)
-	case "roundrobin":
-		return g.pickBackendWithRoundRobin(domain, record, recordType)
-	case "random":
-		return g.pickBackendWithRandom(record, recordType)
-	case "geoip":
-		return g.pickBackendWithGeoIP(record, recordType, clientIP)
-	case "weighted":
-		return g.pickBackendWithWeighted(record, recordType)
-	case "nearest", "closest":
-		return g.pickBackendWithNearest(record, recordType, clientIP)
-	case "fastest":
-		return g.pickBackendWithFastest(record, recordType)
-	default:
-		return nil, fmt.Errorf("unsupported mode: %s", record.Mode)
+	policy := record.Policy
+	if len(policy) == 0 {
+		var ok bool
+		if policy, ok = modePolicy(record.Mode); !ok {
+			return nil, 0, fmt.Errorf("unsupported mode: %s", record.Mode)
+		}
 	}
+	return g.runPolicy(s, policy)
 }
 
 func (g *GSLB) sendAddressRecordResponse(w dns.ResponseWriter, r *dns.Msg, domain string, ipAddresses []string, ttl int, recordType uint16) (int, error) {
 	response := new(dns.Msg)
 	response.SetReply(r)
-	for _, ip := range ipAddresses {
+
+	// A name holding a CNAME cannot hold other data: a single hostname backend is answered with a CNAME,
+	// hostname backends selected alongside others are answered with the addresses they resolved to
+	if len(ipAddresses) == 1 && net.ParseIP(ipAddresses[0]) == nil {
+		response.Answer = g.buildCNAMEAnswer(domain, ipAddresses[0], ttl, recordType)
+		ipAddresses = nil
+	}
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, address := range ipAddresses {
+		resolved := []string{address}
+		if net.ParseIP(address) == nil {
+			resolved = g.resolvedAddresses(domain, address, recordType)
+		}
+		for _, ip := range resolved {
+			if !seen[ip] {
+				seen[ip] = true
+				addresses = append(addresses, ip)
+			}
+		}
+	}
+
+	for _, ip := range addresses {
 		var rr dns.RR
 		switch recordType {
 		case dns.TypeA:
@@ -471,12 +784,77 @@ func (g *GSLB) sendAddressRecordResponse(w dns.ResponseWriter, r *dns.Msg, domai
 	return dns.RcodeSuccess, nil
 }
 
+// buildCNAMEAnswer returns a CNAME from domain to the backend hostname, followed by the
+// addresses of the target resolved during the last health check when they are known.
+func (g *GSLB) buildCNAMEAnswer(domain string, target string, ttl int, recordType uint16) []dns.RR {
+	target = dns.Fqdn(target)
+	answer := []dns.RR{&dns.CNAME{
+		Hdr: dns.RR_Header{
+			Name:   domain,
+			Rrtype: dns.TypeCNAME,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(ttl),
+		},
+		Target: target,
+	}}
+
+	for _, addr := range g.resolvedAddresses(domain, target, recordType) {
+		ip := net.ParseIP(addr)
+		if recordType == dns.TypeA {
+			answer = append(answer, &dns.A{
+				Hdr: dns.RR_Header{Name: target, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: uint32(ttl)},
+				A:   ip,
+			})
+		} else {
+			answer = append(answer, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: target, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: uint32(ttl)},
+				AAAA: ip,
+			})
+		}
+	}
+	return answer
+}
+
+// resolvedAddresses returns the addresses of the record type's family the hostname backend of the record
+// resolved to during its last health check.
+func (g *GSLB) resolvedAddresses(domain string, target string, recordType uint16) []string {
+	record, _ := g.findRecord(domain)
+	if record == nil {
+		return nil
+	}
+	var addresses []string
+	for _, backend := range record.getBackends() {
+		if dns.Fqdn(backend.GetAddress()) != dns.Fqdn(target) {
+			continue
+		}
+		for _, addr := range backend.GetResolvedAddresses() {
+			ip := net.ParseIP(addr)
+			switch {
+			case recordType == dns.TypeA && ip.To4() != nil:
+				addresses = append(addresses, addr)
+			case recordType == dns.TypeAAAA && ip != nil && ip.To4() == nil:
+				addresses = append(addresses, addr)
+			}
+		}
+		break
+	}
+	return addresses
+}
+
 func (g *GSLB) updateRecords(ctx context.Context, newGSLB *GSLB) {
 	for zone, newRecords := range newGSLB.Records {
 		oldRecords, exists := g.Records[zone]
 		if !exists {
-			log.Infof("Not yet implemented: new zone %s", zone)
-			continue
+			// The zone failed to load at startup
+			log.Infof("Adding new zone %s", zone)
+			oldRecords = make(map[string]*Record)
+			g.Records[zone] = oldRecords
+		}
+		if cfg, ok := newGSLB.zoneConfigs[zone]; ok {
+			if g.zoneConfigs == nil {
+				g.zoneConfigs = make(map[string]*zoneConfig)
+			}
+			g.zoneConfigs[zone] = cfg
 		}
 		// This zone exists, update existing records
 		for fqdn, newRecord := range newRecords {
@@ -485,8 +863,7 @@ func (g *GSLB) updateRecords(ctx context.Context, newGSLB *GSLB) {
 				newRecord.Fqdn = fqdn
 				g.Records[zone][fqdn] = newRecord
 				log.Infof("Added new record for zone %s: %s", zone, fqdn)
-				newRecord.updateRecordHealthStatus()
-				go newRecord.scrapeBackends(ctx, g)
+				newRecord.startHealthChecks(ctx, g)
 			} else {
 				log.Infof("Reloading record %s in zone %s", fqdn, zone)
 				oldRecord.updateRecord(newRecord)
@@ -496,15 +873,16 @@ func (g *GSLB) updateRecords(ctx context.Context, newGSLB *GSLB) {
 		// Remove records from old zone that are no longer present in newGSLB.Records
 		for fqdn := range oldRecords {
 			if _, exists := newRecords[fqdn]; !exists {
-				if record := oldRecords[fqdn]; record.cancelFunc != nil {
-					record.cancelFunc()
-				}
+				oldRecords[fqdn].stopHealthChecks()
 				delete(g.Records[zone], fqdn)
 				log.Infof("Records [%s] removed from zone %s", fqdn, zone)
 			}
 		}
 	}
 
+	// Keep the runtime overrides on top of the reloaded zone file
+	g.applyOverrides()
+
 	// Update metrics
 	g.updateMetrics()
 }
@@ -515,22 +893,43 @@ func (g *GSLB) initializeRecordsFromFiles(ctx context.Context, zoneFiles map[str
 		log.Infof("Loading records for zone %s from %s", zone, file)
 		if err := loadConfigFile(g, file, zone); err != nil {
 			log.Errorf("Failed to load records for zone %s from %s: %v", zone, file, err)
+			g.setZoneError(zone, err)
 			continue
 		}
+		g.setZoneSerial(zone)
 		log.Infof("Loaded %d records for zone %s", len(g.Records[zone]), zone)
 	}
+	// Restore the last known health state before the staggered healthchecks start
+	if err := g.restoreState(); err != nil {
+		log.Errorf("Failed to restore state: %v", err)
+	}
+	// The zone file watchers are already running and may reload the records concurrently
+	g.Mutex.Lock()
+	if err := g.loadOverrides(); err != nil {
+		log.Errorf("Failed to load overrides: %v", err)
+	}
+	g.Mutex.Unlock()
 	groups := g.batchRecords(g.BatchSizeStart)
+	// The metrics are tracked before the staggered start: on a Corefile reload, the series of the
+	// previous instance are kept instead of being deleted until the health checks start
+	for _, group := range groups {
+		for _, record := range group {
+			trackRecordMetrics(record.Fqdn)
+		}
+	}
 	for i, group := range groups {
 		go func(group []*Record, delay time.Duration) {
-			time.Sleep(delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				for _, record := range group {
+					releaseRecordMetrics(record.Fqdn)
+				}
+				return
+			}
 			for _, record := range group {
-				domain := record.Fqdn
-				recordCtx, cancel := context.WithCancel(ctx)
-				record.cancelFunc = cancel
-				log.Debugf("[%s] Starting health checks for backends", domain)
-				// Initialize health status for existing record
-				record.updateRecordHealthStatus()
-				go record.scrapeBackends(recordCtx, g)
+				log.Debugf("[%s] Starting health checks for backends", record.Fqdn)
+				record.startTrackedHealthChecks(ctx, g)
 			}
 		}(group, time.Duration(i)*g.staggerDelay(len(groups)))
 	}
@@ -539,6 +938,17 @@ func (g *GSLB) initializeRecordsFromFiles(ctx context.Context, zoneFiles map[str
 	g.updateMetrics()
 }
 
+// stopHealthChecks stops the health checks of all the records, when CoreDNS reloads or stops.
+func (g *GSLB) stopHealthChecks() {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	for _, records := range g.Records {
+		for _, record := range records {
+			record.stopHealthChecks()
+		}
+	}
+}
+
 func (g *GSLB) updateMetrics() {
 	SetZonesTotal(float64(len(g.Records)))
 
@@ -655,11 +1065,58 @@ func (g *GSLB) findRecord(domain string) (*Record, string) {
 	return nil, ""
 }
 
-func loadConfigFile(gslb *GSLB, fileName string, zone string) error {
+// findBackend returns the backend of a record with the given address, or nil.
+func (g *GSLB) findBackend(fqdn, address string) BackendInterface {
+	rec, _ := g.findRecord(fqdn)
+	if rec == nil {
+		return nil
+	}
+	for _, backend := range rec.Backends {
+		if backend.GetAddress() == address {
+			return backend
+		}
+	}
+	return nil
+}
 
-	if !strings.HasSuffix(zone, ".") {
-		zone += "."
+// zoneConfig is the raw content of a zone file. It is kept to apply the changes of the records API
+// with the same validation as the zone file, and to write them back.
+type zoneConfig struct {
+	Defaults            map[string]interface{}  `yaml:"defaults,omitempty"`
+	Records             map[string]interface{}  `yaml:"records"`
+	HealthcheckProfiles map[string]*HealthCheck `yaml:"healthcheck_profiles,omitempty"`
+}
+
+// clone returns a copy of the zone config whose records can be modified.
+func (c *zoneConfig) clone() *zoneConfig {
+	records := make(map[string]interface{}, len(c.Records))
+	for fqdn, data := range c.Records {
+		records[fqdn] = deepCopy(data)
 	}
+	return &zoneConfig{Defaults: c.Defaults, Records: records, HealthcheckProfiles: c.HealthcheckProfiles}
+}
+
+// deepCopy copies the maps and lists of a decoded YAML value.
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = deepCopy(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			l[i] = deepCopy(item)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+func loadConfigFile(gslb *GSLB, fileName string, zone string) error {
 	data, err := os.ReadFile(fileName)
 	if err != nil {
 		return fmt.Errorf("failed to read YAML configuration: %w", err)
@@ -667,15 +1124,19 @@ func loadConfigFile(gslb *GSLB, fileName string, zone string) error {
 	if len(data) == 0 {
 		return fmt.Errorf("failed to read YAML configuration: file empty")
 	}
-	var raw struct {
-		Defaults            map[string]interface{}  `yaml:"defaults"`
-		Records             map[string]interface{}  `yaml:"records"`
-		HealthcheckProfiles map[string]*HealthCheck `yaml:"healthcheck_profiles"`
-	}
-	if err := yaml.Unmarshal(data, &raw); err != nil {
+	var cfg zoneConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return fmt.Errorf("failed to parse YAML configuration: %w", err)
 	}
-	gslb.HealthcheckProfiles = raw.HealthcheckProfiles
+	return loadZoneConfig(gslb, &cfg, zone)
+}
+
+// loadZoneConfig builds the records of a zone from its raw config.
+func loadZoneConfig(gslb *GSLB, cfg *zoneConfig, zone string) error {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	gslb.HealthcheckProfiles = cfg.HealthcheckProfiles
 	if gslb.Records == nil {
 		gslb.Records = make(map[string]map[string]*Record)
 	}
@@ -683,35 +1144,25 @@ func loadConfigFile(gslb *GSLB, fileName string, zone string) error {
 		gslb.Records[zone] = make(map[string]*Record)
 	}
 
-	for fqdn, recordData := range raw.Records {
+	for fqdn, recordData := range cfg.Records {
 		if zone != "" && !strings.HasSuffix(fqdn, zone) {
 			return fmt.Errorf("record %s does not match zone %s", fqdn, zone)
 		}
-		var merged map[string]interface{}
+		recordMap, ok := deepCopy(recordData).(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("record %s is not a map", fqdn)
+		}
+		merged := make(map[string]interface{})
 
 		// handle defaults
-		if raw.Defaults != nil {
-			recordMap, ok := recordData.(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("record %s is not a map", fqdn)
-			}
-			merged = make(map[string]interface{})
-			// copy defaults
-			for k, v := range raw.Defaults {
-				merged[k] = v
-			}
-			// copy record data
-			for k, v := range recordMap {
-				merged[k] = v
-			}
-		} else {
-			var ok bool
-			merged, ok = recordData.(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("record %s is not a map", fqdn)
-			}
+		for k, v := range cfg.Defaults {
+			merged[k] = v
 		}
-		processedRecordData, err := (&GSLB{HealthcheckProfiles: raw.HealthcheckProfiles}).processRecordHealthchecks(merged)
+		// copy record data
+		for k, v := range recordMap {
+			merged[k] = v
+		}
+		processedRecordData, err := (&GSLB{HealthcheckProfiles: cfg.HealthcheckProfiles}).processRecordHealthchecks(merged)
 		if err != nil {
 			return fmt.Errorf("error processing record %s: %w", fqdn, err)
 		}
@@ -726,5 +1177,9 @@ func loadConfigFile(gslb *GSLB, fileName string, zone string) error {
 		record.Fqdn = fqdn
 		gslb.Records[zone][fqdn] = &record
 	}
+	if gslb.zoneConfigs == nil {
+		gslb.zoneConfigs = make(map[string]*zoneConfig)
+	}
+	gslb.zoneConfigs[zone] = cfg
 	return nil
 }
@@ -0,0 +1,165 @@
+package gslb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+)
+
+// authorityTTL is the TTL of the synthesized SOA and NS records in positive answers.
+const authorityTTL = 3600
+
+// authoritativeWriter sets the AA bit on every response written for a zone the plugin owns.
+type authoritativeWriter struct {
+	dns.ResponseWriter
+}
+
+func (w *authoritativeWriter) WriteMsg(m *dns.Msg) error {
+	m.Authoritative = true
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// findZone returns the most specific configured zone containing the domain, or an empty string.
+func (g *GSLB) findZone(domain string) string {
+	zones := make([]string, 0, len(g.Zones))
+	for zone := range g.Zones {
+		zones = append(zones, zone)
+	}
+	return plugin.Zones(zones).Matches(domain)
+}
+
+// serveAuthority answers the queries the records cannot answer when the plugin is authoritative:
+// SOA and NS at the zone apex, NXDOMAIN for unknown names and NODATA for unsupported query types.
+// It returns false when the query must be handled by the record handlers.
+func (g *GSLB) serveAuthority(w dns.ResponseWriter, r *dns.Msg, domain string, qtype uint16) (int, bool, error) {
+	zone := g.findZone(domain)
+	if zone == "" {
+		return 0, false, nil
+	}
+
+	g.Mutex.RLock()
+	record, _ := g.findRecord(domain)
+	g.Mutex.RUnlock()
+
+	if domain == zone && (qtype == dns.TypeSOA || qtype == dns.TypeNS) {
+		response := new(dns.Msg)
+		response.SetReply(r)
+		if qtype == dns.TypeSOA {
+			response.Answer = []dns.RR{g.buildSOA(zone, authorityTTL)}
+		} else {
+			response.Answer = g.buildNS(zone)
+		}
+		rcode, err := g.writeAuthorityResponse(w, response)
+		return rcode, true, err
+	}
+
+	if record == nil {
+		if domain == zone || g.hasRecordsBelow(domain) {
+			rcode, err := g.sendNegativeResponse(w, r, zone, dns.RcodeSuccess)
+			return rcode, true, err
+		}
+		rcode, err := g.sendNegativeResponse(w, r, zone, dns.RcodeNameError)
+		return rcode, true, err
+	}
+
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeSRV, dns.TypeHTTPS, dns.TypeSVCB, dns.TypeTXT:
+		return 0, false, nil
+	default:
+		rcode, err := g.sendNegativeResponse(w, r, zone, dns.RcodeSuccess)
+		return rcode, true, err
+	}
+}
+
+// hasRecordsBelow returns true if the domain is an empty non-terminal, i.e. an ancestor of a record.
+func (g *GSLB) hasRecordsBelow(domain string) bool {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	for _, records := range g.Records {
+		for fqdn := range records {
+			if strings.HasSuffix(fqdn, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendNegativeResponse writes an NXDOMAIN or NODATA response with the zone SOA in the authority section.
+// The SOA TTL is the negative caching TTL, as required by RFC 2308.
+func (g *GSLB) sendNegativeResponse(w dns.ResponseWriter, r *dns.Msg, zone string, rcode int) (int, error) {
+	response := new(dns.Msg)
+	response.SetRcode(r, rcode)
+	response.Ns = []dns.RR{g.buildSOA(zone, g.NegativeTTL)}
+	return g.writeAuthorityResponse(w, response)
+}
+
+func (g *GSLB) writeAuthorityResponse(w dns.ResponseWriter, response *dns.Msg) (int, error) {
+	if err := w.WriteMsg(response); err != nil {
+		log.Error("Failed to write DNS authority response: ", err)
+		return dns.RcodeServerFailure, err
+	}
+	return response.Rcode, nil
+}
+
+// buildSOA returns the synthesized SOA record of a zone.
+func (g *GSLB) buildSOA(zone string, ttl int) *dns.SOA {
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(ttl),
+		},
+		Ns:      g.getNameservers(zone)[0],
+		Mbox:    "hostmaster." + zone,
+		Serial:  g.getZoneSerial(zone),
+		Refresh: 7200,
+		Retry:   3600,
+		Expire:  1209600,
+		Minttl:  uint32(g.NegativeTTL),
+	}
+}
+
+// buildNS returns the synthesized NS records of a zone.
+func (g *GSLB) buildNS(zone string) []dns.RR {
+	var answer []dns.RR
+	for _, ns := range g.getNameservers(zone) {
+		answer = append(answer, &dns.NS{
+			Hdr: dns.RR_Header{
+				Name:   zone,
+				Rrtype: dns.TypeNS,
+				Class:  dns.ClassINET,
+				Ttl:    authorityTTL,
+			},
+			Ns: ns,
+		})
+	}
+	return answer
+}
+
+// getNameservers returns the configured nameservers, or ns1.<zone> if none are set.
+func (g *GSLB) getNameservers(zone string) []string {
+	if len(g.Nameservers) == 0 {
+		return []string{"ns1." + zone}
+	}
+	nameservers := make([]string, 0, len(g.Nameservers))
+	for _, ns := range g.Nameservers {
+		nameservers = append(nameservers, dns.Fqdn(ns))
+	}
+	return nameservers
+}
+
+// setZoneSerial bumps the SOA serial of a zone after its records have been (re)loaded.
+func (g *GSLB) setZoneSerial(zone string) {
+	g.ZoneSerial.Store(zone, uint32(time.Now().Unix()))
+}
+
+func (g *GSLB) getZoneSerial(zone string) uint32 {
+	if value, ok := g.ZoneSerial.Load(zone); ok {
+		return value.(uint32)
+	}
+	return 1
+}
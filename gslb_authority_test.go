@@ -0,0 +1,140 @@
+package gslb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthoritativeGSLB() *GSLB {
+	backend := &Backend{Address: "192.168.1.1", Enable: true, Alive: true, Priority: 1}
+	record := &Record{
+		Fqdn:      "webapp.app.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{backend},
+		RecordTTL: 30,
+	}
+	g := &GSLB{
+		Zones:         map[string]string{"example.com.": "dummy.yml", "app.example.com.": "dummy.yml"},
+		Records:       map[string]map[string]*Record{"app.example.com.": {"webapp.app.example.com.": record}},
+		Authoritative: true,
+		Nameservers:   []string{"ns1.example.net", "ns2.example.net."},
+		NegativeTTL:   45,
+	}
+	g.ZoneSerial.Store("app.example.com.", uint32(2024010101))
+	return g
+}
+
+func TestServeDNS_Authoritative_SOAAndNS(t *testing.T) {
+	g := newAuthoritativeGSLB()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("app.example.com.", dns.TypeSOA)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.True(t, w.msg.Authoritative)
+	assert.Len(t, w.msg.Answer, 1)
+	soa := w.msg.Answer[0].(*dns.SOA)
+	assert.Equal(t, "app.example.com.", soa.Hdr.Name)
+	assert.Equal(t, "ns1.example.net.", soa.Ns)
+	assert.Equal(t, "hostmaster.app.example.com.", soa.Mbox)
+	assert.Equal(t, uint32(2024010101), soa.Serial)
+	assert.Equal(t, uint32(45), soa.Minttl)
+
+	msg.SetQuestion("app.example.com.", dns.TypeNS)
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Answer, 2)
+	assert.Equal(t, "ns2.example.net.", w.msg.Answer[1].(*dns.NS).Ns)
+}
+
+func TestServeDNS_Authoritative_NXDOMAIN(t *testing.T) {
+	g := newAuthoritativeGSLB()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("unknown.app.example.com.", dns.TypeA)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeNameError, code)
+	assert.Equal(t, dns.RcodeNameError, w.msg.Rcode)
+	assert.True(t, w.msg.Authoritative)
+	assert.Empty(t, w.msg.Answer)
+	assert.Len(t, w.msg.Ns, 1)
+	soa := w.msg.Ns[0].(*dns.SOA)
+	assert.Equal(t, "app.example.com.", soa.Hdr.Name, "the most specific zone must be used")
+	assert.Equal(t, uint32(45), soa.Hdr.Ttl)
+}
+
+func TestServeDNS_Authoritative_NODATA(t *testing.T) {
+	g := newAuthoritativeGSLB()
+
+	testCases := []struct {
+		name  string
+		fqdn  string
+		qtype uint16
+	}{
+		{"no backend for the address family", "webapp.app.example.com.", dns.TypeAAAA},
+		{"unsupported query type", "webapp.app.example.com.", dns.TypeMX},
+		{"zone apex without record", "app.example.com.", dns.TypeA},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := new(dns.Msg)
+			msg.SetQuestion(tc.fqdn, tc.qtype)
+			w := &mockResponseWriter{}
+			code, err := g.ServeDNS(context.Background(), w, msg)
+			assert.NoError(t, err)
+			assert.Equal(t, dns.RcodeSuccess, code)
+			assert.Empty(t, w.msg.Answer)
+			assert.Len(t, w.msg.Ns, 1)
+			assert.IsType(t, &dns.SOA{}, w.msg.Ns[0])
+		})
+	}
+}
+
+func TestServeDNS_Authoritative_EmptyNonTerminal(t *testing.T) {
+	g := newAuthoritativeGSLB()
+	g.Records["app.example.com."]["_sip._udp.app.example.com."] = &Record{Fqdn: "_sip._udp.app.example.com."}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("_udp.app.example.com.", dns.TypeA)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Ns, 1)
+}
+
+func TestServeDNS_Authoritative_Answer(t *testing.T) {
+	g := newAuthoritativeGSLB()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("webapp.app.example.com.", dns.TypeA)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.True(t, w.msg.Authoritative)
+	assert.Len(t, w.msg.Answer, 1)
+}
+
+func TestServeDNS_NotAuthoritative_PassesUnknownNames(t *testing.T) {
+	g := newAuthoritativeGSLB()
+	g.Authoritative = false
+	n := &nextPlugin{}
+	g.Next = n
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("unknown.app.example.com.", dns.TypeA)
+	w := &mockResponseWriter{}
+	_, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.True(t, n.called)
+}
@@ -2,246 +2,519 @@ package gslb
 
 import (
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/rand"
 	"net"
 	"sort"
-	"time"
 
 	"github.com/miekg/dns"
+	"github.com/oschwald/maxminddb-golang"
 )
 
-// pickBackendWithFailover returns all healthy backends with the lowest priority.
-func (g *GSLB) pickBackendWithFailover(record *Record, recordType uint16) ([]string, error) {
-	sortedBackends := make([]BackendInterface, len(record.Backends))
-	copy(sortedBackends, record.Backends)
-	sort.Slice(sortedBackends, func(i, j int) bool {
-		return sortedBackends[i].GetPriority() < sortedBackends[j].GetPriority()
-	})
+func init() {
+	RegisterSelector("failover", SelectorFunc(selectFailover))
+	RegisterSelector("roundrobin", SelectorFunc(selectRoundRobin))
+	RegisterSelector("random", SelectorFunc(selectRandom))
+	RegisterSelector("weighted", SelectorFunc(selectWeighted))
+	RegisterSelector("fastest", SelectorFunc(selectFastest))
+	RegisterSelector("least_loaded", SelectorFunc(selectLeastLoaded))
+	RegisterSelector("hash", SelectorFunc(selectHash))
+	RegisterSelector("sticky", SelectorFunc(selectHash))
+	RegisterSelector("nearest", SelectorFunc(selectNearest))
+	RegisterSelector("geoip_country", SelectorFunc(selectGeoIPCountry))
+	RegisterSelector("geoip_city", SelectorFunc(selectGeoIPCity))
+	RegisterSelector("geoip_asn", SelectorFunc(selectGeoIPASN))
+	RegisterSelector("geoip_location", SelectorFunc(selectGeoIPLocation))
+	RegisterSelector("geoip", SelectorFunc(selectGeoIP))
+
+	RegisterPolicy("closest", "nearest")
+}
 
-	minPriority := -1
-	var healthyIPs []string
-	for _, backend := range sortedBackends {
-		if backend.IsHealthy() {
-			ip := backend.GetAddress()
-			if (recordType == dns.TypeA && net.ParseIP(ip).To4() != nil) ||
-				(recordType == dns.TypeAAAA && net.ParseIP(ip).To16() != nil && net.ParseIP(ip).To4() == nil) {
-				if minPriority == -1 {
-					minPriority = backend.GetPriority()
-				}
-				if backend.GetPriority() == minPriority {
-					healthyIPs = append(healthyIPs, ip)
-					IncBackendSelected(record.Fqdn, ip)
-				} else {
-					break // stop at first higher priority
-				}
-			}
-		}
+// answerCount returns how many of n candidates a mode answers: the record max_answers if set,
+// the mode default otherwise (0 for all of them).
+func (r *Record) answerCount(modeDefault, n int) int {
+	count := modeDefault
+	if r.MaxAnswers > 0 {
+		count = r.MaxAnswers
 	}
-
-	if len(healthyIPs) == 0 {
-		return nil, fmt.Errorf("no healthy backends in failover mode for type %d", recordType)
+	if count <= 0 || count > n {
+		count = n
 	}
+	return count
+}
 
-	return healthyIPs, nil
+// pickBackendWithFailover returns the healthy backends with the lowest priority, all of them by default.
+func (g *GSLB) pickBackendWithFailover(record *Record, recordType uint16) ([]string, error) {
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, recordType, nil, []string{"failover"})
+	return ips, err
 }
 
-// pickBackendWithRoundRobin returns one healthy backend in round-robin order.
+// pickBackendWithRoundRobin returns a window of healthy backends, one by default, rotating by one
+// backend on every query.
 func (g *GSLB) pickBackendWithRoundRobin(domain string, record *Record, recordType uint16) ([]string, error) {
-	g.Mutex.Lock()
-	defer g.Mutex.Unlock()
+	ips, _, err := g.pickBackendWithPolicy(domain, record, recordType, nil, []string{"roundrobin"})
+	return ips, err
+}
 
-	var index int
-	value, exists := g.RoundRobinIndex.Load(domain)
-	if exists {
-		index = value.(int)
-	}
+// pickBackendWithRandom returns the healthy backends in random order, all of them by default.
+func (g *GSLB) pickBackendWithRandom(record *Record, recordType uint16) ([]string, error) {
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, recordType, nil, []string{"random"})
+	return ips, err
+}
 
-	healthyBackends := []BackendInterface{}
+// pickBackendWithWeighted returns healthy backends, one by default, selected proportionally to their
+// weight without replacement.
+func (g *GSLB) pickBackendWithWeighted(record *Record, recordType uint16) ([]string, error) {
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, recordType, nil, []string{"weighted"})
+	return ips, err
+}
+
+// pickBackendWithNearestCoordinates selects the closest backends based on provided coordinates, nearest first.
+func (g *GSLB) pickBackendWithNearestCoordinates(record *Record, recordType uint16, clientLat, clientLon float64) ([]string, error) {
+	var candidates []BackendInterface
 	for _, backend := range record.Backends {
-		if backend.IsHealthy() {
-			ip := backend.GetAddress()
-			if (recordType == dns.TypeA && net.ParseIP(ip).To4() != nil) ||
-				(recordType == dns.TypeAAAA && net.ParseIP(ip).To16() != nil && net.ParseIP(ip).To4() == nil) {
-				healthyBackends = append(healthyBackends, backend)
-			}
+		if backend.IsHealthy() && backend.IsEnabled() && backendMatchesType(backend, recordType) {
+			candidates = append(candidates, backend)
 		}
 	}
+	candidates = nearestBackends(candidates, clientLat, clientLon)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no healthy backends with coordinates in nearest mode for type %d", recordType)
+	}
 
-	if len(healthyBackends) == 0 {
-		return nil, fmt.Errorf("no healthy backends in round-robin mode for type %d", recordType)
+	addresses := []string{}
+	for _, backend := range candidates[:record.answerCount(1, len(candidates))] {
+		addresses = append(addresses, backend.GetAddress())
+		IncBackendSelected(record.Fqdn, backend.GetAddress())
 	}
+	return addresses, nil
+}
 
-	selectedBackend := healthyBackends[index%len(healthyBackends)]
-	g.RoundRobinIndex.Store(domain, (index+1)%len(healthyBackends))
-	IncBackendSelected(record.Fqdn, selectedBackend.GetAddress())
+// nearestBackends returns the backends with coordinates, nearest to the given coordinates first.
+func nearestBackends(backends []BackendInterface, lat, lon float64) []BackendInterface {
+	var nearest []BackendInterface
+	distances := make(map[BackendInterface]float64)
+	for _, backend := range backends {
+		if backend.HasCoordinates() {
+			nearest = append(nearest, backend)
+			distances[backend] = haversineKm(lat, lon, backend.GetLatitude(), backend.GetLongitude())
+		}
+	}
+	sort.SliceStable(nearest, func(i, j int) bool {
+		return distances[nearest[i]] < distances[nearest[j]]
+	})
+	return nearest
+}
 
-	return []string{selectedBackend.GetAddress()}, nil
+// pickBackendWithFastest returns the healthy backends with the lowest recorded health check response time,
+// one by default, fastest first.
+// Backends that have not yet been health checked are deprioritised (treated as slowest).
+func (g *GSLB) pickBackendWithFastest(record *Record, recordType uint16) ([]string, error) {
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, recordType, nil, []string{"fastest"})
+	return ips, err
 }
 
-// pickBackendWithRandom returns all healthy backends in random order.
-func (g *GSLB) pickBackendWithRandom(record *Record, recordType uint16) ([]string, error) {
-	g.Mutex.Lock()
-	defer g.Mutex.Unlock()
+// pickBackendWithGeoIP implements advanced GeoIP routing: country, city, ASN, custom location, with fallback to failover.
+// Country, city and ASN routing answer one matching backend by default, custom location routing all of them.
+// The ECS scope is the most specific network of the client consulted: MaxMind network or location subnet.
+func (g *GSLB) pickBackendWithGeoIP(record *Record, recordType uint16, clientIP net.IP) ([]string, uint8, error) {
+	return g.pickBackendWithPolicy(record.Fqdn, record, recordType, &ClientInfo{IP: clientIP}, []string{"geoip"})
+}
 
-	healthyBackends := []BackendInterface{}
-	for _, backend := range record.Backends {
-		if backend.IsHealthy() {
-			ip := backend.GetAddress()
-			if (recordType == dns.TypeA && net.ParseIP(ip).To4() != nil) ||
-				(recordType == dns.TypeAAAA && net.ParseIP(ip).To16() != nil && net.ParseIP(ip).To4() == nil) {
-				healthyBackends = append(healthyBackends, backend)
-			}
+// selectFailover keeps the candidates with the lowest priority, and answers all of them.
+func selectFailover(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	minPriority := candidates[0].GetPriority()
+	for _, backend := range candidates {
+		minPriority = min(minPriority, backend.GetPriority())
+	}
+	var kept []BackendInterface
+	for _, backend := range candidates {
+		if backend.GetPriority() == minPriority {
+			kept = append(kept, backend)
 		}
 	}
+	s.Answers = 0
+	return kept, nil
+}
+
+// selectRoundRobin rotates the candidates by one on every query of the domain, and answers one of them.
+// A preview gives the next rotation without advancing it.
+func selectRoundRobin(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	g := s.GSLB
+	if !s.Preview {
+		g.Mutex.Lock()
+		defer g.Mutex.Unlock()
+	}
 
-	if len(healthyBackends) == 0 {
-		return nil, fmt.Errorf("no healthy backends in random mode for type %d", recordType)
+	var index int
+	if value, exists := g.RoundRobinIndex.Load(s.Domain); exists {
+		index = value.(int) % len(candidates)
 	}
+	if !s.Preview {
+		g.RoundRobinIndex.Store(s.Domain, (index+1)%len(candidates))
+	}
+
+	rotated := make([]BackendInterface, 0, len(candidates))
+	rotated = append(rotated, candidates[index:]...)
+	rotated = append(rotated, candidates[:index]...)
+	s.Answers = 1
+	return rotated, nil
+}
 
-	// Shuffle healthy backends to create random order
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	r.Shuffle(len(healthyBackends), func(i, j int) {
-		healthyBackends[i], healthyBackends[j] = healthyBackends[j], healthyBackends[i]
+// selectRandom shuffles the candidates, and answers all of them.
+func selectRandom(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	shuffled := append([]BackendInterface(nil), candidates...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
+	s.Answers = 0
+	return shuffled, nil
+}
 
-	// Collect the shuffled IPs
-	addresses := []string{}
-	for _, backend := range healthyBackends {
-		addresses = append(addresses, backend.GetAddress())
-		IncBackendSelected(record.Fqdn, backend.GetAddress())
+// selectWeighted orders the candidates by sampling them proportionally to their weight without
+// replacement, and answers one of them.
+func selectWeighted(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	remaining := append([]BackendInterface(nil), candidates...)
+	totalWeight := 0
+	for _, backend := range remaining {
+		totalWeight += backend.GetWeight()
 	}
+	ordered := make([]BackendInterface, 0, len(remaining))
+	for len(remaining) > 0 {
+		// Roulette wheel selection among the backends not selected yet
+		randVal := rand.Intn(totalWeight)
+		cumulative := 0
+		for i, backend := range remaining {
+			cumulative += backend.GetWeight()
+			if randVal < cumulative {
+				ordered = append(ordered, backend)
+				totalWeight -= backend.GetWeight()
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	s.Answers = 1
+	return ordered, nil
+}
 
-	return addresses, nil
+// selectFastest orders the candidates by health check response time, the ones not measured yet last,
+// and answers one of them.
+func selectFastest(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	var measured, unmeasured []BackendInterface
+	for _, backend := range candidates {
+		if backend.GetResponseTime() == 0 {
+			// Not yet measured; only use as a candidate if nothing better is available.
+			unmeasured = append(unmeasured, backend)
+			continue
+		}
+		measured = append(measured, backend)
+	}
+	sort.SliceStable(measured, func(i, j int) bool {
+		return measured[i].GetResponseTime() < measured[j].GetResponseTime()
+	})
+	s.Answers = 1
+	return append(measured, unmeasured...), nil
 }
 
-// pickBackendWithWeighted returns one healthy backend, selected proportionally to its weight.
-func (g *GSLB) pickBackendWithWeighted(record *Record, recordType uint16) ([]string, error) {
-	var weightedBackends []BackendInterface
-	var totalWeight int
-	for _, backend := range record.Backends {
-		if backend.IsHealthy() && backend.IsEnabled() {
-			ip := backend.GetAddress()
-			if (recordType == dns.TypeA && net.ParseIP(ip).To4() != nil) ||
-				(recordType == dns.TypeAAAA && net.ParseIP(ip).To16() != nil && net.ParseIP(ip).To4() == nil) {
-				w := backend.GetWeight()
-				if w > 0 {
-					weightedBackends = append(weightedBackends, backend)
-					totalWeight += w
-				}
+// selectLeastLoaded keeps the candidates of the lowest priority with spare capacity, least loaded first,
+// and answers one of them.
+func selectLeastLoaded(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	type candidate struct {
+		backend     BackendInterface
+		utilization float64
+		reported    bool
+	}
+	var available []candidate
+	for _, backend := range candidates {
+		load, reported := backend.GetLoad()
+		utilization := load / float64(backend.GetWeight())
+		if capacity := backend.GetCapacity(); capacity > 0 {
+			if reported && load >= capacity {
+				continue // Full, spill over
 			}
+			utilization = load / capacity
 		}
+		available = append(available, candidate{backend, utilization, reported})
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("every backend is at capacity")
 	}
-	if len(weightedBackends) == 0 || totalWeight == 0 {
-		return nil, fmt.Errorf("no healthy backends with weight > 0 for type %d", recordType)
-	}
-	// Roulette wheel selection
-	randVal := rand.Intn(totalWeight)
-	cumulative := 0
-	for _, backend := range weightedBackends {
-		cumulative += backend.GetWeight()
-		if randVal < cumulative {
-			IncBackendSelected(record.Fqdn, backend.GetAddress())
-			return []string{backend.GetAddress()}, nil
+
+	sort.SliceStable(available, func(i, j int) bool {
+		a, b := available[i], available[j]
+		if a.backend.GetPriority() != b.backend.GetPriority() {
+			return a.backend.GetPriority() < b.backend.GetPriority()
+		}
+		if a.reported != b.reported {
+			return a.reported
 		}
+		return a.utilization < b.utilization
+	})
+	// Backends of a higher priority are never mixed in
+	var kept []BackendInterface
+	for _, c := range available {
+		if c.backend.GetPriority() != available[0].backend.GetPriority() {
+			break
+		}
+		kept = append(kept, c.backend)
 	}
-	// Should not reach here
-	return nil, fmt.Errorf("weighted selection failed")
+	s.Answers = 1
+	return kept, nil
 }
 
-// pickBackendWithNearest returns the single healthy backend closest to the client based on GeoIP city lat/long.
-func (g *GSLB) pickBackendWithNearest(record *Record, recordType uint16, clientIP net.IP) ([]string, error) {
-	if g.GeoIPCityDB == nil {
-		return g.pickBackendWithFailover(record, recordType)
+// selectHash orders the candidates by weighted rendezvous hash score for the client network, and answers
+// one of them. The candidates are kept unchanged without a client.
+func selectHash(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	if s.Client == nil || s.Client.IP == nil {
+		return candidates, nil
 	}
-
-	recordCity, err := g.GeoIPCityDB.City(clientIP)
-	if err != nil || recordCity == nil {
-		return g.pickBackendWithFailover(record, recordType)
+	network := clientNetwork(s.Client)
+	scores := make(map[BackendInterface]float64, len(candidates))
+	for _, backend := range candidates {
+		scores[backend] = rendezvousScore(network, backend.GetAddress(), backend.GetWeight())
 	}
+	ordered := append([]BackendInterface(nil), candidates...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i]] > scores[ordered[j]]
+	})
+	s.WidenScope(s.Client.PrefixLen)
+	s.Answers = 1
+	return ordered, nil
+}
 
-	ips, err := g.pickBackendWithNearestCoordinates(record, recordType, recordCity.Location.Latitude, recordCity.Location.Longitude)
-	if err != nil {
-		return g.pickBackendWithFailover(record, recordType)
+// selectNearest orders the candidates with coordinates by distance to the client located with the MaxMind
+// city database, nearest first, and answers one of them. The candidates go through failover when the client
+// cannot be located or none has coordinates.
+func selectNearest(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	g := s.GSLB
+	if g.GeoIPCityDB == nil || s.Client == nil || s.Client.IP == nil {
+		return selectFailover(s, candidates)
+	}
+	s.WidenScope(networkPrefix(g.GeoIPCityNetworks, s.Client.IP))
+	city, err := g.GeoIPCityDB.City(s.Client.IP)
+	if err != nil || city == nil {
+		return selectFailover(s, candidates)
 	}
-	return ips, nil
+	nearest := nearestBackends(candidates, city.Location.Latitude, city.Location.Longitude)
+	if len(nearest) == 0 {
+		return selectFailover(s, candidates)
+	}
+	s.Answers = 1
+	return nearest, nil
 }
 
-// pickBackendWithNearestCoordinates selects the closest backend based on provided coordinates.
-func (g *GSLB) pickBackendWithNearestCoordinates(record *Record, recordType uint16, clientLat, clientLon float64) ([]string, error) {
-	var best BackendInterface
-	bestDistance := math.MaxFloat64
-
-	for _, backend := range record.Backends {
-		if !backend.IsHealthy() || !backend.IsEnabled() {
+// selectGeoIP keeps the candidates matching the client country, or else its city, ASN or custom location,
+// and falls back to failover when none matches. Country, city and ASN routing answer one of them, custom
+// location routing all of them.
+func selectGeoIP(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	sources := []struct {
+		match   clientMatcher
+		answers int
+	}{{matchCountry, 1}, {matchCity, 1}, {matchASN, 1}, {matchLocation, 0}}
+	for _, source := range sources {
+		match, ok := source.match(s)
+		if !ok {
 			continue
 		}
-		if !backend.HasCoordinates() {
-			continue
-		}
-		ip := backend.GetAddress()
-		if (recordType == dns.TypeA && net.ParseIP(ip).To4() == nil) ||
-			(recordType == dns.TypeAAAA && (net.ParseIP(ip).To16() == nil || net.ParseIP(ip).To4() != nil)) {
-			continue
-		}
-		distance := haversineKm(clientLat, clientLon, backend.GetLatitude(), backend.GetLongitude())
-		if distance < bestDistance {
-			bestDistance = distance
-			best = backend
+		if matched := matchingBackends(candidates, match); len(matched) > 0 {
+			s.Answers = source.answers
+			return matched, nil
 		}
 	}
+	return selectFailover(s, candidates)
+}
 
-	if best == nil {
-		return nil, fmt.Errorf("no healthy backends with coordinates in nearest mode for type %d", recordType)
+// selectGeoIPCountry keeps the candidates in the country of the client, from the MaxMind country database.
+func selectGeoIPCountry(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	return keepLocated(s, candidates, matchCountry), nil
+}
+
+// selectGeoIPCity keeps the candidates in the city of the client, from the MaxMind city database.
+func selectGeoIPCity(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	return keepLocated(s, candidates, matchCity), nil
+}
+
+// selectGeoIPASN keeps the candidates in the autonomous system of the client, from the MaxMind ASN database.
+func selectGeoIPASN(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	return keepLocated(s, candidates, matchASN), nil
+}
+
+// selectGeoIPLocation keeps the candidates in the location of the client, from the most specific
+// subnet of the custom location map containing it.
+func selectGeoIPLocation(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	return keepLocated(s, candidates, matchLocation), nil
+}
+
+// clientMatcher locates the client of a selection, and returns whether a backend is in its location.
+// It returns false when the client cannot be located, and widens the scope to the network consulted.
+type clientMatcher func(s *Selection) (func(BackendInterface) bool, bool)
+
+// matchCountry locates the client with the MaxMind country database.
+func matchCountry(s *Selection) (func(BackendInterface) bool, bool) {
+	g := s.GSLB
+	if g.GeoIPCountryDB == nil || s.Client == nil || s.Client.IP == nil {
+		return nil, false
 	}
+	s.WidenScope(networkPrefix(g.GeoIPCountryNetworks, s.Client.IP))
+	country, err := g.GeoIPCountryDB.Country(s.Client.IP)
+	if err != nil || country == nil || country.Country.IsoCode == "" {
+		return nil, false
+	}
+	return func(backend BackendInterface) bool {
+		return backend.GetCountry() == country.Country.IsoCode
+	}, true
+}
 
-	IncBackendSelected(record.Fqdn, best.GetAddress())
-	return []string{best.GetAddress()}, nil
+// matchCity locates the client with the MaxMind city database.
+func matchCity(s *Selection) (func(BackendInterface) bool, bool) {
+	g := s.GSLB
+	if g.GeoIPCityDB == nil || s.Client == nil || s.Client.IP == nil {
+		return nil, false
+	}
+	s.WidenScope(networkPrefix(g.GeoIPCityNetworks, s.Client.IP))
+	city, err := g.GeoIPCityDB.City(s.Client.IP)
+	if err != nil || city == nil || city.City.Names["en"] == "" {
+		return nil, false
+	}
+	return func(backend BackendInterface) bool {
+		return backend.GetCity() == city.City.Names["en"]
+	}, true
 }
 
-// pickBackendWithFastest returns the single healthy backend with the lowest recorded health check response time.
-// Backends that have not yet been health checked are deprioritised (treated as slowest).
-// Falls back to failover if no healthy backend has a recorded response time.
-func (g *GSLB) pickBackendWithFastest(record *Record, recordType uint16) ([]string, error) {
-	var best BackendInterface
-	bestTime := time.Duration(math.MaxInt64)
-	hasAnyMeasured := false
+// matchASN locates the client with the MaxMind ASN database.
+func matchASN(s *Selection) (func(BackendInterface) bool, bool) {
+	g := s.GSLB
+	if g.GeoIPASNDB == nil || s.Client == nil || s.Client.IP == nil {
+		return nil, false
+	}
+	s.WidenScope(networkPrefix(g.GeoIPASNNetworks, s.Client.IP))
+	asn, err := g.GeoIPASNDB.ASN(s.Client.IP)
+	if err != nil || asn == nil || asn.AutonomousSystemNumber == 0 {
+		return nil, false
+	}
+	return func(backend BackendInterface) bool {
+		return backend.GetASN() == fmt.Sprint(asn.AutonomousSystemNumber)
+	}, true
+}
 
-	for _, backend := range record.Backends {
-		if !backend.IsHealthy() || !backend.IsEnabled() {
-			continue
-		}
-		ip := backend.GetAddress()
-		if (recordType == dns.TypeA && net.ParseIP(ip).To4() == nil) ||
-			(recordType == dns.TypeAAAA && (net.ParseIP(ip).To16() == nil || net.ParseIP(ip).To4() != nil)) {
-			continue
-		}
-		rt := backend.GetResponseTime()
-		if rt == 0 {
-			// Not yet measured; only use as a candidate if nothing better is available.
-			if best == nil {
-				best = backend
-			}
-			continue
-		}
-		hasAnyMeasured = true
-		if rt < bestTime {
-			bestTime = rt
-			best = backend
+// matchLocation locates the client with the most specific subnet of the custom location map containing it.
+func matchLocation(s *Selection) (func(BackendInterface) bool, bool) {
+	g := s.GSLB
+	g.Mutex.RLock()
+	locationMap := g.LocationMap
+	g.Mutex.RUnlock()
+	if len(locationMap) == 0 || s.Client == nil || s.Client.IP == nil {
+		return nil, false
+	}
+	s.WidenScope(locationPrefix(locationMap, s.Client.IP))
+	location, ok := clientLocation(locationMap, s.Client.IP)
+	if !ok {
+		return nil, false
+	}
+	return func(backend BackendInterface) bool {
+		return backend.GetLocation() == location
+	}, true
+}
+
+// keepLocated returns the candidates in the location of the client, or all of them if the client cannot be
+// located, so that the next stage decides.
+func keepLocated(s *Selection, candidates []BackendInterface, locate clientMatcher) []BackendInterface {
+	match, ok := locate(s)
+	if !ok {
+		return candidates
+	}
+	return keepMatching(candidates, match)
+}
+
+// keepMatching returns the candidates matching, or all of them if none does, so that the next stage decides.
+func keepMatching(candidates []BackendInterface, match func(BackendInterface) bool) []BackendInterface {
+	if kept := matchingBackends(candidates, match); len(kept) > 0 {
+		return kept
+	}
+	return candidates
+}
+
+// matchingBackends returns the backends matching, in order.
+func matchingBackends(backends []BackendInterface, match func(BackendInterface) bool) []BackendInterface {
+	var matched []BackendInterface
+	for _, backend := range backends {
+		if match(backend) {
+			matched = append(matched, backend)
 		}
 	}
+	return matched
+}
 
-	if best == nil {
-		return nil, fmt.Errorf("no healthy backends in fastest mode for type %d", recordType)
+// clientNetwork returns the client address truncated to its prefix length, the key of the hash mode.
+func clientNetwork(client *ClientInfo) net.IP {
+	ip, bits := client.IP.To4(), 32
+	if ip == nil {
+		ip, bits = client.IP.To16(), 128
 	}
+	return ip.Mask(net.CIDRMask(min(int(client.PrefixLen), bits), bits))
+}
+
+// rendezvousScore returns the weighted rendezvous hash score of a backend for a client network:
+// -weight/ln(h) with h uniform in (0,1), so that each backend wins a share of the clients
+// proportional to its weight.
+func rendezvousScore(network net.IP, address string, weight int) float64 {
+	h := fnv.New64a()
+	h.Write(network)
+	h.Write([]byte(address))
+	// Finalizer of MurmurHash3, FNV alone does not spread short keys over the high bits
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	u := (float64(x>>11) + 0.5) / (1 << 53)
+	return -float64(weight) / math.Log(u)
+}
 
-	// If we found measured backends but best ended up being an unmeasured one, fall back to failover.
-	if hasAnyMeasured && best.GetResponseTime() == 0 {
-		return g.pickBackendWithFailover(record, recordType)
+// backendMatchesType returns true if the backend can answer a query of the given type.
+// IP backends must match the address family; CNAME backends answer both A and AAAA queries.
+// SRV answers need a port, and a target for IP backends.
+func backendMatchesType(backend BackendInterface, recordType uint16) bool {
+	if recordType == dns.TypeSRV {
+		return backend.GetPort() > 0 && srvTarget(backend) != ""
 	}
+	if backend.IsCNAME() {
+		return recordType == dns.TypeA || recordType == dns.TypeAAAA
+	}
+	ip := net.ParseIP(backend.GetAddress())
+	switch recordType {
+	case dns.TypeA:
+		return ip.To4() != nil
+	case dns.TypeAAAA:
+		return ip != nil && ip.To4() == nil
+	default:
+		return false
+	}
+}
 
-	IncBackendSelected(record.Fqdn, best.GetAddress())
-	return []string{best.GetAddress()}, nil
+// addressPrefix returns the length of the IP address in bits.
+func addressPrefix(ip net.IP) uint8 {
+	if ip.To4() != nil {
+		return 32
+	}
+	return 128
+}
+
+// networkPrefix returns the prefix length of the MaxMind network containing the IP, or the address length
+// if unknown, so that the answer is only cached for the client address.
+func networkPrefix(db *maxminddb.Reader, ip net.IP) uint8 {
+	if db == nil {
+		return addressPrefix(ip)
+	}
+	network, _, err := db.LookupNetwork(ip, &struct{}{})
+	if err != nil || network == nil {
+		return addressPrefix(ip)
+	}
+	ones, _ := network.Mask.Size()
+	return uint8(ones)
 }
 
 func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
@@ -261,100 +534,36 @@ func degreesToRadians(deg float64) float64 {
 	return deg * math.Pi / 180.0
 }
 
-// pickBackendWithGeoIP implements advanced GeoIP routing: country, city, ASN, custom location, with fallback to failover.
-func (g *GSLB) pickBackendWithGeoIP(record *Record, recordType uint16, clientIP net.IP) ([]string, error) {
-	// 1. Country-based routing (highest priority)
-	if g.GeoIPCountryDB != nil {
-		recordCountry, err := g.GeoIPCountryDB.Country(clientIP)
-		if err == nil && recordCountry != nil && recordCountry.Country.IsoCode != "" {
-			countryCode := recordCountry.Country.IsoCode
-			var matchedIPs []string
-			for _, backend := range record.Backends {
-				if backend.IsHealthy() && backend.IsEnabled() {
-					if backend.GetCountry() == countryCode {
-						matchedIPs = append(matchedIPs, backend.GetAddress())
-						IncBackendSelected(record.Fqdn, backend.GetAddress())
-						break
-					}
-				}
-			}
-			if len(matchedIPs) > 0 {
-				return matchedIPs, nil
-			}
+// clientLocation returns the location of the most specific subnet of the location map containing the IP.
+func clientLocation(locationMap map[string]string, ip net.IP) (string, bool) {
+	var location string
+	prefix := -1
+	for subnet, loc := range locationMap {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil || !ipnet.Contains(ip) {
+			continue
 		}
-	}
-
-	// 2. City-based routing (if city DB loaded)
-	if g.GeoIPCityDB != nil {
-		recordCity, err := g.GeoIPCityDB.City(clientIP)
-		if err == nil && recordCity != nil && recordCity.City.Names != nil {
-			cityName := recordCity.City.Names["en"]
-			if cityName != "" {
-				var matchedIPs []string
-				for _, backend := range record.Backends {
-					if backend.IsHealthy() && backend.IsEnabled() {
-						if backend.GetCity() == cityName {
-							matchedIPs = append(matchedIPs, backend.GetAddress())
-							IncBackendSelected(record.Fqdn, backend.GetAddress())
-							break
-						}
-					}
-				}
-				if len(matchedIPs) > 0 {
-					return matchedIPs, nil
-				}
-			}
+		if ones, _ := ipnet.Mask.Size(); ones > prefix {
+			location, prefix = loc, ones
 		}
 	}
+	return location, prefix >= 0
+}
 
-	// 3. ASN-based routing (if ASN DB loaded)
-	if g.GeoIPASNDB != nil {
-		recordASN, err := g.GeoIPASNDB.ASN(clientIP)
-		if err == nil && recordASN != nil && recordASN.AutonomousSystemNumber != 0 {
-			asn := fmt.Sprint(recordASN.AutonomousSystemNumber)
-			var matchedIPs []string
-			for _, backend := range record.Backends {
-				if backend.IsHealthy() && backend.IsEnabled() {
-					if backend.GetASN() == asn {
-						matchedIPs = append(matchedIPs, backend.GetAddress())
-						IncBackendSelected(record.Fqdn, backend.GetAddress())
-						break
-					}
-				}
-			}
-			if len(matchedIPs) > 0 {
-				return matchedIPs, nil
-			}
+// locationPrefix returns the prefix length of the most specific location subnet containing the IP,
+// or the address length if none does.
+func locationPrefix(locationMap map[string]string, ip net.IP) uint8 {
+	var prefix uint8
+	for subnet := range locationMap {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil || !ipnet.Contains(ip) {
+			continue
 		}
+		ones, _ := ipnet.Mask.Size()
+		prefix = max(prefix, uint8(ones))
 	}
-
-	// 4. Custom location map (subnet to location string)
-	g.Mutex.RLock()
-	locationMap := g.LocationMap
-	g.Mutex.RUnlock()
-	if len(locationMap) > 0 {
-		var matchedIPs []string
-		for _, backend := range record.Backends {
-			if backend.IsHealthy() && backend.IsEnabled() {
-				loc := backend.GetLocation()
-				for subnet, location := range locationMap {
-					_, ipnet, err := net.ParseCIDR(subnet)
-					if err == nil && ipnet.Contains(clientIP) {
-						if loc == location {
-							matchedIPs = append(matchedIPs, backend.GetAddress())
-							IncBackendSelected(record.Fqdn, backend.GetAddress())
-							break
-						}
-						break
-					}
-				}
-			}
-		}
-		if len(matchedIPs) > 0 {
-			return matchedIPs, nil
-		}
+	if prefix == 0 {
+		return addressPrefix(ip)
 	}
-
-	// 5. Fallback: failover (priority order)
-	return g.pickBackendWithFailover(record, recordType)
+	return prefix
 }
@@ -90,6 +90,28 @@ func TestGSLB_PickBackendWithFailover_MultipleSamePriority(t *testing.T) {
 	assert.Contains(t, ipAddresses, "192.168.1.2")
 }
 
+func TestGSLB_PickBackendWithFailover_CNAME(t *testing.T) {
+	backendCNAME := &MockBackend{Backend: &Backend{Address: "lb-eu.cloudprovider.net", Enable: true, Priority: 10}}
+	backendIPv4 := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true, Priority: 20}}
+	backendCNAME.On("IsHealthy").Return(true)
+	backendIPv4.On("IsHealthy").Return(true)
+
+	record := &Record{
+		Fqdn:     "example.com.",
+		Mode:     "failover",
+		Backends: []BackendInterface{backendIPv4, backendCNAME},
+	}
+
+	g := &GSLB{}
+
+	// CNAME backends answer both address families
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		ipAddresses, _, err := g.pickBackendWithPolicy(record.Fqdn, record, qtype, nil, []string{"failover"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"lb-eu.cloudprovider.net"}, ipAddresses)
+	}
+}
+
 func TestGSLB_PickBackendWithRoundRobin_IPv4(t *testing.T) {
 	// Create mock backends with IPv4 addresses
 	backend1 := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true}}
@@ -249,7 +271,7 @@ func TestGSLB_PickBackendWithGeoIP_CustomDB(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ips, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
+			ips, _, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expect, ips)
 		})
@@ -258,7 +280,7 @@ func TestGSLB_PickBackendWithGeoIP_CustomDB(t *testing.T) {
 	// Test fallback when LocationMap is nil
 	g.LocationMap = nil
 	t.Run("fallback no location map", func(t *testing.T) {
-		ips, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP("8.8.8.8"))
+		ips, _, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP("8.8.8.8"))
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"10.0.0.42"}, ips)
 	})
@@ -300,7 +322,7 @@ func TestGSLB_PickBackendWithGeoIP_Country_MaxMind(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ips, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
+			ips, _, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expect, ips)
 		})
@@ -343,7 +365,7 @@ func TestGSLB_PickBackendWithGeoIP_City_MaxMind(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ips, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
+			ips, _, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expect, ips)
 		})
@@ -386,7 +408,7 @@ func TestGSLB_PickBackendWithGeoIP_ASN_MaxMind(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ips, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
+			ips, _, err := g.pickBackendWithGeoIP(record, dns.TypeA, net.ParseIP(tc.clientIP))
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expect, ips)
 		})
@@ -464,6 +486,28 @@ func TestGSLB_PickBackendWithNearestCoordinates_NoCandidates(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGSLB_PickResponse_NearestFallsBackToFailover(t *testing.T) {
+	primary := &MockBackend{Backend: &Backend{Address: "192.168.1.10", Enable: true, Priority: 1, Latitude: 52.5200, Longitude: 13.4050, CoordinatesSet: true}}
+	secondary := &MockBackend{Backend: &Backend{Address: "192.168.1.20", Enable: true, Priority: 2, Latitude: 48.8566, Longitude: 2.3522, CoordinatesSet: true}}
+	primary.On("IsHealthy").Return(true)
+	secondary.On("IsHealthy").Return(true)
+
+	for _, mode := range []string{"nearest", "closest"} {
+		record := &Record{
+			Fqdn:     "nearest.example.com.",
+			Mode:     mode,
+			Backends: []BackendInterface{secondary, primary},
+		}
+		g := &GSLB{Records: map[string]map[string]*Record{"example.com.": {record.Fqdn: record}}}
+
+		// Without a city database the client cannot be located
+		ips, scope, err := g.pickResponse(record.Fqdn, dns.TypeA, &ClientInfo{IP: net.ParseIP("81.185.159.80"), PrefixLen: 32})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"192.168.1.10"}, ips)
+		assert.Equal(t, uint8(0), scope)
+	}
+}
+
 func TestGSLB_PickBackendWithFastest_SelectsSlowest(t *testing.T) {
 	backendFast := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true, ResponseTime: 10 * time.Millisecond}}
 	backendSlow := &MockBackend{Backend: &Backend{Address: "192.168.1.2", Enable: true, ResponseTime: 100 * time.Millisecond}}
@@ -571,6 +615,244 @@ func TestGSLB_PickBackendWithFastest_IPv6(t *testing.T) {
 	assert.Equal(t, []string{"2001:db8::1"}, ips)
 }
 
+// newMaxAnswersBackends returns healthy mock backends with the given addresses.
+func newMaxAnswersBackends(addresses ...string) []*MockBackend {
+	backends := make([]*MockBackend, len(addresses))
+	for i, address := range addresses {
+		backends[i] = &MockBackend{Backend: &Backend{Address: address, Enable: true, Priority: 1, Weight: 1}}
+		backends[i].On("IsHealthy").Return(true)
+	}
+	return backends
+}
+
+func TestGSLB_MaxAnswers_FailoverAndRandom(t *testing.T) {
+	backends := newMaxAnswersBackends("10.0.0.1", "10.0.0.2", "10.0.0.3")
+	backends[2].Priority = 2
+	record := &Record{
+		Fqdn:       "max.example.com.",
+		MaxAnswers: 3,
+		Backends:   []BackendInterface{backends[0], backends[1], backends[2]},
+	}
+	g := &GSLB{}
+
+	// Failover never answers a backend of a higher priority
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"failover"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, ips)
+	record.MaxAnswers = 1
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"failover"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+
+	record.MaxAnswers = 2
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"random"})
+	assert.NoError(t, err)
+	assert.Len(t, ips, 2)
+	assert.NotEqual(t, ips[0], ips[1])
+}
+
+func TestGSLB_MaxAnswers_RoundRobin(t *testing.T) {
+	backends := newMaxAnswersBackends("10.0.0.1", "10.0.0.2", "10.0.0.3")
+	record := &Record{
+		Fqdn:       "rr.example.com.",
+		MaxAnswers: 2,
+		Backends:   []BackendInterface{backends[0], backends[1], backends[2]},
+	}
+	g := &GSLB{}
+
+	// The window rotates by one backend on every query
+	expected := [][]string{
+		{"10.0.0.1", "10.0.0.2"},
+		{"10.0.0.2", "10.0.0.3"},
+		{"10.0.0.3", "10.0.0.1"},
+	}
+	for _, want := range expected {
+		ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"roundrobin"})
+		assert.NoError(t, err)
+		assert.Equal(t, want, ips)
+	}
+}
+
+func TestGSLB_MaxAnswers_Weighted(t *testing.T) {
+	backends := newMaxAnswersBackends("10.0.0.1", "10.0.0.2", "10.0.0.3")
+	backends[0].Weight = 8
+	record := &Record{
+		Fqdn:       "weighted.example.com.",
+		MaxAnswers: 2,
+		Backends:   []BackendInterface{backends[0], backends[1], backends[2]},
+	}
+	g := &GSLB{}
+
+	// Sampled without replacement, the heaviest backend is answered first most of the time
+	first := 0
+	for i := 0; i < 1000; i++ {
+		ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"weighted"})
+		assert.NoError(t, err)
+		assert.Len(t, ips, 2)
+		assert.NotEqual(t, ips[0], ips[1])
+		if ips[0] == "10.0.0.1" {
+			first++
+		}
+	}
+	assert.InDelta(t, 0.8, float64(first)/1000, 0.06)
+
+	// No more answers than backends
+	record.MaxAnswers = 5
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"weighted"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, ips)
+}
+
+func TestGSLB_MaxAnswers_NearestAndFastest(t *testing.T) {
+	paris := &MockBackend{Backend: &Backend{Address: "10.0.0.1", Enable: true, Latitude: 48.8566, Longitude: 2.3522, CoordinatesSet: true, ResponseTime: 30 * time.Millisecond}}
+	berlin := &MockBackend{Backend: &Backend{Address: "10.0.0.2", Enable: true, Latitude: 52.5200, Longitude: 13.4050, CoordinatesSet: true}}
+	london := &MockBackend{Backend: &Backend{Address: "10.0.0.3", Enable: true, Latitude: 51.5074, Longitude: -0.1278, CoordinatesSet: true, ResponseTime: 10 * time.Millisecond}}
+	for _, b := range []*MockBackend{paris, berlin, london} {
+		b.On("IsHealthy").Return(true)
+	}
+	record := &Record{
+		Fqdn:       "nearest.example.com.",
+		MaxAnswers: 2,
+		Backends:   []BackendInterface{berlin, paris, london},
+	}
+	g := &GSLB{}
+
+	// The nearest backends from Brussels, nearest first
+	ips, err := g.pickBackendWithNearestCoordinates(record, dns.TypeA, 50.8503, 4.3517)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, ips)
+
+	// The fastest backends, the unmeasured one last
+	ips, err = g.pickBackendWithFastest(record, dns.TypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.1"}, ips)
+	record.MaxAnswers = 3
+	ips, err = g.pickBackendWithFastest(record, dns.TypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}, ips)
+}
+
+func TestGSLB_PickBackendWithLeastLoaded(t *testing.T) {
+	backends := []*Backend{
+		{Address: "10.0.0.1", Enable: true, Alive: true, Priority: 1, Capacity: 100, Load: 60, LoadReported: true},
+		{Address: "10.0.0.2", Enable: true, Alive: true, Priority: 1, Capacity: 500, Load: 200, LoadReported: true},
+		{Address: "10.0.0.3", Enable: true, Alive: true, Priority: 1, Capacity: 100},
+		{Address: "10.0.0.4", Enable: true, Alive: true, Priority: 2, Weight: 2, Load: 30, LoadReported: true},
+		{Address: "10.0.0.5", Enable: true, Alive: true, Priority: 2, Weight: 1, Load: 20, LoadReported: true},
+	}
+	record := &Record{
+		Fqdn:     "load.example.com.",
+		Mode:     "least_loaded",
+		Backends: []BackendInterface{backends[0], backends[1], backends[2], backends[3], backends[4]},
+	}
+	g := &GSLB{}
+
+	// Ranked by load relative to capacity, the backend without a reported load last
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, ips)
+	record.MaxAnswers = 5
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.1", "10.0.0.3"}, ips)
+
+	// Full backends are skipped
+	backends[1].Load = 500
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, ips)
+
+	// Once the priority is full, the next one is used, ranked by load relative to weight
+	backends[0].Load = 100
+	backends[2].Load, backends[2].LoadReported = 150, true
+	record.MaxAnswers = 0
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.4"}, ips)
+	backends[3].Load = 50
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5"}, ips)
+
+	// Everything full or down
+	backends[3].Alive, backends[4].Alive = false, false
+	_, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, nil, []string{"least_loaded"})
+	assert.Error(t, err)
+}
+
+// hashClients returns the backend picked in hash mode for each /24 client network of 10.0.0.0/8.
+func hashClients(t *testing.T, g *GSLB, record *Record, n int) []string {
+	picked := make([]string, n)
+	for i := range picked {
+		client := &ClientInfo{IP: net.IPv4(10, byte(i>>8), byte(i), 0), PrefixLen: 24}
+		ips, scope, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client, []string{"hash"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(24), scope)
+		picked[i] = ips[0]
+	}
+	return picked
+}
+
+func TestGSLB_PickBackendWithHash(t *testing.T) {
+	backends := []*Backend{
+		{Address: "192.168.1.1", Enable: true, Alive: true, Weight: 2},
+		{Address: "192.168.1.2", Enable: true, Alive: true, Weight: 1},
+		{Address: "192.168.1.3", Enable: true, Alive: true, Weight: 1},
+	}
+	record := &Record{
+		Fqdn:     "sticky.example.com.",
+		Mode:     "hash",
+		Backends: []BackendInterface{backends[0], backends[1], backends[2]},
+	}
+	g := &GSLB{}
+
+	// The clients of a network stick to the same backend
+	for _, host := range []string{"10.1.2.3", "10.1.2.200", "10.1.2.3"} {
+		ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, &ClientInfo{IP: net.ParseIP(host), PrefixLen: 24}, []string{"hash"})
+		assert.NoError(t, err)
+		first, _, _ := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, &ClientInfo{IP: net.ParseIP("10.1.2.0"), PrefixLen: 24}, []string{"hash"})
+		assert.Equal(t, first, ips)
+	}
+
+	// The clients are shared proportionally to the weights
+	const clients = 4000
+	before := hashClients(t, g, record, clients)
+	share := map[string]int{}
+	for _, ip := range before {
+		share[ip]++
+	}
+	assert.InDelta(t, 0.5, float64(share["192.168.1.1"])/clients, 0.04)
+	assert.InDelta(t, 0.25, float64(share["192.168.1.2"])/clients, 0.04)
+	assert.InDelta(t, 0.25, float64(share["192.168.1.3"])/clients, 0.04)
+
+	// Only the clients of a failed backend are remapped, and they come back once it recovers
+	backends[1].Alive = false
+	after := hashClients(t, g, record, clients)
+	for i := range before {
+		if before[i] == "192.168.1.2" {
+			assert.NotEqual(t, "192.168.1.2", after[i])
+		} else {
+			assert.Equal(t, before[i], after[i])
+		}
+	}
+	backends[1].Alive = true
+	assert.Equal(t, before, hashClients(t, g, record, clients))
+
+	// With max_answers, the next backends in score order follow the sticky one
+	record.MaxAnswers = 2
+	ips, _, err := g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, &ClientInfo{IP: net.ParseIP("10.0.0.0"), PrefixLen: 24}, []string{"hash"})
+	assert.NoError(t, err)
+	assert.Len(t, ips, 2)
+	assert.Equal(t, before[0], ips[0])
+	assert.NotEqual(t, ips[0], ips[1])
+
+	for _, b := range backends {
+		b.Alive = false
+	}
+	_, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, &ClientInfo{IP: net.ParseIP("10.0.0.0"), PrefixLen: 24}, []string{"hash"})
+	assert.Error(t, err)
+}
+
 // TestResponseWriter is a mock dns.ResponseWriter for testing
 // It captures the DNS message sent by WriteMsg
 type TestResponseWriter struct {
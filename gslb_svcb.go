@@ -0,0 +1,122 @@
+package gslb
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/miekg/dns"
+)
+
+// handleSVCBRecord answers HTTPS and SVCB queries with a ServiceMode record whose address hints
+// are taken from the backends currently selected for A and AAAA queries.
+func (g *GSLB) handleSVCBRecord(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, domain string, recordType uint16) (int, error) {
+	record, _ := g.findRecord(domain)
+	if record == nil {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+	ci := GetClientInfo(ctx)
+	if ci == nil || ci.IP == nil {
+		log.Error("No client info in context")
+		return dns.RcodeServerFailure, nil
+	}
+
+	answer := g.answerWriter(ctx, w, r)
+	svcb := g.buildSVCB(record, domain, ci)
+	if svcb == nil && g.Authoritative {
+		return g.sendNegativeResponse(answer, r, g.findZone(domain), dns.RcodeSuccess)
+	}
+
+	response := new(dns.Msg)
+	response.SetReply(r)
+	if svcb != nil {
+		svcb.Hdr.Rrtype = recordType
+		if recordType == dns.TypeHTTPS {
+			response.Answer = append(response.Answer, &dns.HTTPS{SVCB: *svcb})
+		} else {
+			response.Answer = append(response.Answer, svcb)
+		}
+	}
+
+	if err := answer.WriteMsg(response); err != nil {
+		log.Error("Failed to write DNS SVCB response: ", err)
+		IncRecordResolutions(domain, "fail")
+		return dns.RcodeServerFailure, err
+	}
+	IncRecordResolutions(domain, "success")
+	return dns.RcodeSuccess, nil
+}
+
+// buildSVCB returns the ServiceMode record for a GSLB record, or nil if no backend can be announced.
+// When the selected backend is a CNAME backend, its hostname becomes the target and no hints are given.
+func (g *GSLB) buildSVCB(record *Record, domain string, ci *ClientInfo) *dns.SVCB {
+	ipv4 := g.pickHintAddresses(domain, dns.TypeA, ci)
+	ipv6 := g.pickHintAddresses(domain, dns.TypeAAAA, ci)
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		return nil
+	}
+
+	svcb := &dns.SVCB{
+		Hdr: dns.RR_Header{
+			Name:  domain,
+			Class: dns.ClassINET,
+			Ttl:   uint32(record.GetTTL()),
+		},
+		Priority: record.HTTPS.GetPriority(),
+		Target:   ".",
+	}
+
+	var v4Hints, v6Hints []net.IP
+	for _, addr := range append(ipv4, ipv6...) {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			// The first selected backend decides, as for A/AAAA answers
+			if len(v4Hints) == 0 && len(v6Hints) == 0 && svcb.Target == "." {
+				svcb.Target = dns.Fqdn(addr)
+			}
+		case ip.To4() != nil:
+			v4Hints = append(v4Hints, ip.To4())
+		default:
+			v6Hints = append(v6Hints, ip)
+		}
+	}
+	if svcb.Target != "." {
+		v4Hints, v6Hints = nil, nil
+	}
+
+	// SvcParams must be sorted by key: alpn, port, ipv4hint, ech, ipv6hint
+	if len(record.HTTPS.ALPN) > 0 {
+		svcb.Value = append(svcb.Value, &dns.SVCBAlpn{Alpn: record.HTTPS.ALPN})
+	}
+	if record.HTTPS.Port > 0 {
+		svcb.Value = append(svcb.Value, &dns.SVCBPort{Port: uint16(record.HTTPS.Port)})
+	}
+	if len(v4Hints) > 0 {
+		svcb.Value = append(svcb.Value, &dns.SVCBIPv4Hint{Hint: v4Hints})
+	}
+	if record.HTTPS.ECH != "" {
+		if ech, err := base64.StdEncoding.DecodeString(record.HTTPS.ECH); err == nil {
+			svcb.Value = append(svcb.Value, &dns.SVCBECHConfig{ECH: ech})
+		}
+	}
+	if len(v6Hints) > 0 {
+		svcb.Value = append(svcb.Value, &dns.SVCBIPv6Hint{Hint: v6Hints})
+	}
+	return svcb
+}
+
+// pickHintAddresses returns the addresses that would be answered for the given type, without failing.
+// The selection is a preview: the hints do not advance the round robin of A and AAAA queries nor count
+// as answers. The ECS scope of the client info is widened to the one of the addresses.
+func (g *GSLB) pickHintAddresses(domain string, recordType uint16, ci *ClientInfo) []string {
+	addresses, scope, err := g.previewResponse(domain, recordType, ci)
+	ci.Scope = max(ci.Scope, scope)
+	if err != nil {
+		if record, _ := g.findRecord(domain); record != nil {
+			addresses, _, _ = g.fallbackAddresses(record, recordType)
+		}
+	}
+	return addresses
+}
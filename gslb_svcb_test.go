@@ -0,0 +1,144 @@
+package gslb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDNS_HTTPS(t *testing.T) {
+	backendV4 := &Backend{Address: "192.168.1.1", Enable: true, Alive: true, Priority: 1}
+	backendV6 := &Backend{Address: "2001:db8::1", Enable: true, Alive: true, Priority: 1}
+	backendDown := &Backend{Address: "192.168.1.2", Enable: true, Alive: false, Priority: 1}
+	record := &Record{
+		Fqdn:      "webapp.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{backendV4, backendV6, backendDown},
+		RecordTTL: 30,
+		HTTPS:     HTTPSConfig{ALPN: []string{"h2", "http/1.1"}, Port: 8443, ECH: "AEX+DQBB"},
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {"webapp.example.com.": record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("webapp.example.com.", dns.TypeHTTPS)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Answer, 1)
+
+	https, ok := w.msg.Answer[0].(*dns.HTTPS)
+	assert.True(t, ok)
+	assert.Equal(t, dns.TypeHTTPS, https.Hdr.Rrtype)
+	assert.Equal(t, uint32(30), https.Hdr.Ttl)
+	assert.Equal(t, uint16(1), https.Priority)
+	assert.Equal(t, ".", https.Target)
+	assert.Equal(t, `alpn="h2,http/1.1" port="8443" ipv4hint="192.168.1.1" ech="AEX+DQBB" ipv6hint="2001:db8::1"`, svcbParams(https.Value))
+}
+
+func TestServeDNS_SVCB_CNAMEBackend(t *testing.T) {
+	backend := &Backend{Address: "lb-eu.cloudprovider.net", Enable: true, Alive: true, Priority: 1}
+	record := &Record{
+		Fqdn:      "webapp.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{backend},
+		RecordTTL: 30,
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {"webapp.example.com.": record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("webapp.example.com.", dns.TypeSVCB)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Answer, 1)
+
+	svcb, ok := w.msg.Answer[0].(*dns.SVCB)
+	assert.True(t, ok)
+	assert.Equal(t, dns.TypeSVCB, svcb.Hdr.Rrtype)
+	assert.Equal(t, "lb-eu.cloudprovider.net.", svcb.Target)
+	assert.Empty(t, svcb.Value)
+}
+
+func TestServeDNS_HTTPS_NoBackends(t *testing.T) {
+	record := &Record{
+		Fqdn:      "webapp.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{},
+		RecordTTL: 30,
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {"webapp.example.com.": record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("webapp.example.com.", dns.TypeHTTPS)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Empty(t, w.msg.Answer)
+}
+
+func TestServeDNS_HTTPS_HintsDoNotAdvanceRoundRobin(t *testing.T) {
+	record := &Record{
+		Fqdn: "rr.example.com.",
+		Mode: "roundrobin",
+		Backends: []BackendInterface{
+			&Backend{Address: "192.168.1.1", Enable: true, Alive: true},
+			&Backend{Address: "192.168.1.2", Enable: true, Alive: true},
+		},
+		RecordTTL: 30,
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {"rr.example.com.": record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+	query := func(qtype uint16) *dns.Msg {
+		msg := new(dns.Msg)
+		msg.SetQuestion("rr.example.com.", qtype)
+		w := &mockResponseWriter{}
+		_, err := g.ServeDNS(context.Background(), w, msg)
+		assert.NoError(t, err)
+		return w.msg
+	}
+
+	// The hints are the next A answer, and neither move the rotation nor count as selections
+	selected := testutil.ToFloat64(backendSelected.WithLabelValues("rr.example.com.", "192.168.1.1"))
+	https := query(dns.TypeHTTPS).Answer[0].(*dns.HTTPS)
+	assert.Equal(t, `ipv4hint="192.168.1.1"`, svcbParams(https.Value))
+	assert.Equal(t, selected, testutil.ToFloat64(backendSelected.WithLabelValues("rr.example.com.", "192.168.1.1")))
+	assert.Equal(t, "192.168.1.1", query(dns.TypeA).Answer[0].(*dns.A).A.String())
+	assert.Equal(t, "192.168.1.2", query(dns.TypeA).Answer[0].(*dns.A).A.String())
+
+	// Nor does the fallback of the hints count
+	for _, backend := range record.Backends {
+		backend.(*Backend).Alive = false
+	}
+	fallbacks := testutil.ToFloat64(recordFallback.WithLabelValues("rr.example.com.", FallbackAll))
+	https = query(dns.TypeHTTPS).Answer[0].(*dns.HTTPS)
+	assert.Equal(t, `ipv4hint="192.168.1.1,192.168.1.2"`, svcbParams(https.Value))
+	assert.Equal(t, fallbacks, testutil.ToFloat64(recordFallback.WithLabelValues("rr.example.com.", FallbackAll)))
+}
+
+func svcbParams(values []dns.SVCBKeyValue) string {
+	var s string
+	for i, v := range values {
+		if i > 0 {
+			s += " "
+		}
+		s += v.Key().String() + `="` + v.String() + `"`
+	}
+	return s
+}
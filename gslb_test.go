@@ -4,11 +4,14 @@ import (
 	"context"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
@@ -208,6 +211,96 @@ func TestGSLB_PickAllAddresses_UnknownDomain(t *testing.T) {
 	assert.Nil(t, ipAddresses, "Expected no IP addresses to be returned")
 }
 
+func TestGSLB_PickFallbackAddresses(t *testing.T) {
+	backend1 := &Backend{Address: "192.168.1.1", Enable: true, Priority: 10}
+	backend2 := &Backend{Address: "192.168.1.2", Enable: true, Priority: 20}
+
+	testCases := []struct {
+		name        string
+		record      *Record
+		recordType  uint16
+		expected    []string
+		expectError bool
+	}{
+		{"all", &Record{Fallback: FallbackAll}, dns.TypeA, []string{"192.168.1.1", "192.168.1.2"}, false},
+		{"default is all", &Record{}, dns.TypeA, []string{"192.168.1.1", "192.168.1.2"}, false},
+		{"none", &Record{Fallback: FallbackNone}, dns.TypeA, nil, true},
+		{"servfail alias", &Record{Fallback: "servfail"}, dns.TypeA, nil, true},
+		{"static IPv4", &Record{Fallback: FallbackStatic, FallbackAddresses: []string{"10.0.0.1", "2001:db8::1"}}, dns.TypeA, []string{"10.0.0.1"}, false},
+		{"static IPv6", &Record{Fallback: FallbackStatic, FallbackAddresses: []string{"10.0.0.1", "2001:db8::1"}}, dns.TypeAAAA, []string{"2001:db8::1"}, false},
+		{"static no matching family", &Record{Fallback: FallbackStatic, FallbackAddresses: []string{"10.0.0.1"}}, dns.TypeAAAA, nil, true},
+		{"cname", &Record{Fallback: FallbackCNAME, FallbackCNAME: "sorry.example.com."}, dns.TypeA, []string{"sorry.example.com."}, false},
+		{"last healthy", &Record{Fallback: FallbackLastHealthy, lastHealthy: []BackendInterface{backend2}}, dns.TypeA, []string{"192.168.1.2"}, false},
+		{"last healthy never healthy", &Record{Fallback: FallbackLastHealthy}, dns.TypeA, []string{"192.168.1.1", "192.168.1.2"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.record.Fqdn = "example.com."
+			tc.record.Backends = []BackendInterface{backend1, backend2}
+			g := &GSLB{
+				Records: map[string]map[string]*Record{"example.com.": {"example.com.": tc.record}},
+			}
+
+			addresses, err := g.pickFallbackAddresses(tc.record, tc.recordType)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expected, addresses)
+		})
+	}
+}
+
+func TestGSLB_HandleIPRecord_FallbackNone(t *testing.T) {
+	backend := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true, Priority: 1}}
+	backend.On("IsHealthy").Return(false)
+	record := &Record{
+		Fqdn:      "example.com.",
+		Mode:      "failover",
+		Fallback:  FallbackNone,
+		Backends:  []BackendInterface{backend},
+		RecordTTL: 60,
+	}
+	g := &GSLB{
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+		Records: map[string]map[string]*Record{"example.com.": {"example.com.": record}},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeServerFailure, code)
+	assert.Nil(t, w.msg, "No answer should be written when fallback is none")
+
+	// The authoritative mode must not turn the SERVFAIL into NODATA
+	g.Authoritative = true
+	g.NegativeTTL = 60
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeServerFailure, code)
+
+	// A record without IPv6 backend has no AAAA data, it is not a failure
+	fallbacks := testutil.ToFloat64(recordFallback.WithLabelValues("example.com.", FallbackNone))
+	msg.SetQuestion("example.com.", dns.TypeAAAA)
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Empty(t, w.msg.Answer)
+	assert.IsType(t, &dns.SOA{}, w.msg.Ns[0])
+
+	n := &nextPlugin{}
+	g.Authoritative = false
+	g.Next = n
+	_, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.True(t, n.called)
+	assert.Equal(t, fallbacks, testutil.ToFloat64(recordFallback.WithLabelValues("example.com.", FallbackNone)))
+}
+
 func TestGSLB_HandleTXTRecord(t *testing.T) {
 	// Create mock backends
 	backend1 := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true, Priority: 10}}
@@ -261,6 +354,8 @@ func TestGSLB_HandleTXTRecord(t *testing.T) {
 			}
 		}
 	}
+	assert.Equal(t, "Record: example.com. | Mode: failover | Fallback: all | TTL: 60", w.Msg.Answer[0].(*dns.TXT).Txt[0])
+	assert.Equal(t, "HealthcheckPolicy: all | Healthchecks: ", w.Msg.Answer[1].(*dns.TXT).Txt[1])
 	assert.True(t, found1, "Expected TXT record for backend1 with LastHealthcheck and ResponseTime")
 	assert.True(t, found2, "Expected TXT record for backend2 with LastHealthcheck and ResponseTime")
 }
@@ -413,6 +508,63 @@ func TestGSLB_SendAddressRecordResponse(t *testing.T) {
 	}
 }
 
+func TestGSLB_SendAddressRecordResponse_CNAME(t *testing.T) {
+	backend := &Backend{
+		Address:           "lb-eu.cloudprovider.net",
+		Enable:            true,
+		ResolvedAddresses: []string{"203.0.113.10", "2001:db8::10"},
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{
+			"example.com.": {
+				"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}},
+			},
+		},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("app.example.com.", dns.TypeA)
+	w := &TestResponseWriter{}
+
+	code, err := g.sendAddressRecordResponse(w, msg, "app.example.com.", []string{"lb-eu.cloudprovider.net"}, 30, dns.TypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.Msg.Answer, 2)
+
+	cname, ok := w.Msg.Answer[0].(*dns.CNAME)
+	assert.True(t, ok)
+	assert.Equal(t, "app.example.com.", cname.Hdr.Name)
+	assert.Equal(t, "lb-eu.cloudprovider.net.", cname.Target)
+	assert.Equal(t, uint32(30), cname.Hdr.Ttl)
+
+	a, ok := w.Msg.Answer[1].(*dns.A)
+	assert.True(t, ok)
+	assert.Equal(t, "lb-eu.cloudprovider.net.", a.Hdr.Name)
+	assert.Equal(t, "203.0.113.10", a.A.String())
+
+	// Hostname backends selected alongside others are answered with their resolved addresses
+	w = &TestResponseWriter{}
+	code, err = g.sendAddressRecordResponse(w, msg, "app.example.com.", []string{"192.168.1.1", "lb-eu.cloudprovider.net", "203.0.113.10"}, 30, dns.TypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	var answered []string
+	for _, rr := range w.Msg.Answer {
+		a, ok := rr.(*dns.A)
+		assert.True(t, ok)
+		assert.Equal(t, "app.example.com.", a.Hdr.Name)
+		answered = append(answered, a.A.String())
+	}
+	assert.Equal(t, []string{"192.168.1.1", "203.0.113.10"}, answered)
+
+	w = &TestResponseWriter{}
+	msg.SetQuestion("app.example.com.", dns.TypeAAAA)
+	_, err = g.sendAddressRecordResponse(w, msg, "app.example.com.", []string{"2001:db8::1", "lb-eu.cloudprovider.net"}, 30, dns.TypeAAAA)
+	assert.NoError(t, err)
+	if assert.Len(t, w.Msg.Answer, 2) {
+		assert.Equal(t, "2001:db8::10", w.Msg.Answer[1].(*dns.AAAA).AAAA.String())
+	}
+}
+
 // TestServeDNS validates the ServeDNS method for various FQDN cases
 func TestServeDNS(t *testing.T) {
 	backend := &Backend{Address: "192.168.1.1", Enable: true, Priority: 1}
@@ -504,6 +656,113 @@ func TestServeDNS_DisableTXT(t *testing.T) {
 	assert.False(t, n.called, "Next plugin should NOT be called when DisableTXT is false")
 }
 
+func TestServeDNS_SRV(t *testing.T) {
+	primary := &Backend{Address: "192.168.1.1", Enable: true, Alive: true, Priority: 10, Weight: 5, Port: 5060, Target: "sip-eu.example.com"}
+	secondary := &Backend{Address: "sip-us.example.net", Enable: true, Alive: true, Priority: 20, Weight: 1, Port: 5061}
+	down := &Backend{Address: "192.168.1.3", Enable: true, Alive: false, Priority: 1, Port: 5060, Target: "sip-down.example.com"}
+	noPort := &Backend{Address: "192.168.1.4", Enable: true, Alive: true, Priority: 1, Target: "sip-noport.example.com"}
+	record := &Record{
+		Fqdn:      "_sip._udp.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{secondary, down, noPort, primary},
+		RecordTTL: 60,
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {"_sip._udp.example.com.": record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("_sip._udp.example.com.", dns.TypeSRV)
+	w := &mockResponseWriter{}
+	code, err := g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Answer, 2)
+
+	first := w.msg.Answer[0].(*dns.SRV)
+	assert.Equal(t, uint16(10), first.Priority)
+	assert.Equal(t, uint16(5), first.Weight)
+	assert.Equal(t, uint16(5060), first.Port)
+	assert.Equal(t, "sip-eu.example.com.", first.Target)
+
+	second := w.msg.Answer[1].(*dns.SRV)
+	assert.Equal(t, uint16(20), second.Priority)
+	assert.Equal(t, "sip-us.example.net.", second.Target)
+
+	// IP backends are glued to their target in the additional section
+	assert.Len(t, w.msg.Extra, 1)
+	glue := w.msg.Extra[0].(*dns.A)
+	assert.Equal(t, "sip-eu.example.com.", glue.Hdr.Name)
+	assert.Equal(t, "192.168.1.1", glue.A.String())
+
+	// Without a healthy backend the fallback policy applies, as for A/AAAA queries
+	primary.Alive, secondary.Alive = false, false
+	w = &mockResponseWriter{}
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Len(t, w.msg.Answer, 3)
+
+	record.Fallback = FallbackNone
+	w = &mockResponseWriter{}
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeServerFailure, code)
+	assert.Nil(t, w.msg)
+
+	// The static policy has no port to announce: SERVFAIL, or NODATA when authoritative, as for A/AAAA queries
+	record.Fallback = FallbackStatic
+	record.FallbackAddresses = []string{"192.0.2.10"}
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeServerFailure, code)
+
+	g.Authoritative = true
+	w = &mockResponseWriter{}
+	_, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Empty(t, w.msg.Answer)
+
+	// A record without backend to announce has no SRV data, it is not a failure
+	record.Backends = []BackendInterface{noPort}
+	w = &mockResponseWriter{}
+	code, err = g.ServeDNS(context.Background(), w, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, dns.RcodeSuccess, code)
+	assert.Empty(t, w.msg.Answer)
+	assert.IsType(t, &dns.SOA{}, w.msg.Ns[0])
+}
+
+func TestServeDNS_SRV_CountsAnsweredBackends(t *testing.T) {
+	up := &Backend{Address: "192.168.1.1", Enable: true, Alive: true, Port: 5060, Target: "sip-up.example.com"}
+	down := &Backend{Address: "192.168.1.2", Enable: true, Alive: false, Port: 5060, Target: "sip-down.example.com"}
+	record := &Record{
+		Fqdn:      "_sip._udp.count.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{up, down},
+		RecordTTL: 60,
+	}
+	g := &GSLB{
+		Records: map[string]map[string]*Record{"example.com.": {record.Fqdn: record}},
+		Zones:   map[string]string{"example.com.": "dummy.yml"},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(record.Fqdn, dns.TypeSRV)
+	_, err := g.ServeDNS(context.Background(), &mockResponseWriter{}, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(backendSelected.WithLabelValues(record.Fqdn, "192.168.1.1")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(backendSelected.WithLabelValues(record.Fqdn, "192.168.1.2")))
+
+	// Fallback answers are counted as fallback, not as selected backends
+	up.Alive = false
+	_, err = g.ServeDNS(context.Background(), &mockResponseWriter{}, msg)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(backendSelected.WithLabelValues(record.Fqdn, "192.168.1.1")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(recordFallback.WithLabelValues(record.Fqdn, FallbackAll)))
+}
+
 // Test UnmarshalYAML with healthcheck profiles
 func TestGSLB_UnmarshalYAML_WithHealthcheckProfiles(t *testing.T) {
 	yamlData := `
@@ -930,3 +1189,191 @@ func writeTempYAML(t *testing.T, content string) string {
 	f.Close()
 	return f.Name()
 }
+
+func TestGSLB_UpdateRecords_NewZone(t *testing.T) {
+	zoneFile := writeTempYAML(t, "records: [")
+	defer os.Remove(zoneFile)
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t.Cleanup(configReloads.Reset)
+
+	// The zone fails to load at startup, then is fixed
+	g.initializeRecordsFromFiles(ctx, g.Zones)
+	assert.NotContains(t, g.Records, "example.com.")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(`
+records:
+  app.example.com.:
+    scrape_interval: 50ms
+    backends:
+      - address: 10.0.0.1
+        enable: false
+`), 0644))
+	assert.NoError(t, reloadConfig(g, zoneFile, "example.com."))
+
+	// The records of the new zone are health checked
+	record := g.Records["example.com."]["app.example.com."]
+	assert.NotNil(t, record)
+	assert.Eventually(t, record.healthChecked, 2*time.Second, 20*time.Millisecond)
+	g.stopHealthChecks()
+}
+
+func TestGSLB_StopHealthChecks(t *testing.T) {
+	RegisterMetrics()
+	record := &Record{Fqdn: "stopped.example.com.", ScrapeInterval: "50ms"}
+	g := &GSLB{Records: map[string]map[string]*Record{"example.com.": {record.Fqdn: record}}}
+	record.startHealthChecks(context.Background(), g)
+	assert.True(t, hasRecordMetrics(t, record.Fqdn))
+
+	// The metric series of the record are deleted once its health checks are stopped
+	g.stopHealthChecks()
+	assert.Eventually(t, func() bool { return !hasRecordMetrics(t, record.Fqdn) }, time.Second, 10*time.Millisecond)
+}
+
+func TestGSLB_InitializeRecords_KeepsMetricsOnReload(t *testing.T) {
+	RegisterMetrics()
+	names := []string{"reloaded1.example.com.", "reloaded2.example.com."}
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(`
+records:
+  reloaded1.example.com.:
+    backends:
+      - address: 10.0.0.1
+        enable: false
+  reloaded2.example.com.:
+    backends:
+      - address: 10.0.0.2
+        enable: false
+`), 0644))
+	hasMetrics := func() bool {
+		return hasRecordMetrics(t, names[0]) && hasRecordMetrics(t, names[1])
+	}
+
+	// The instance before the Corefile reload
+	old := &GSLB{Records: map[string]map[string]*Record{"example.com.": {}}}
+	for _, name := range names {
+		record := &Record{Fqdn: name, ScrapeInterval: "1h"}
+		old.Records["example.com."][name] = record
+		record.startHealthChecks(context.Background(), old)
+	}
+	assert.True(t, hasMetrics())
+
+	// The series are kept while the second batch of the new instance waits for its staggered start
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}, MaxStaggerStart: "1h", BatchSizeStart: 1}
+	g.initializeRecordsFromFiles(ctx, g.Zones)
+	old.stopHealthChecks()
+	assert.Never(t, func() bool { return !hasMetrics() }, 200*time.Millisecond, 10*time.Millisecond)
+
+	// And deleted once the new instance stops too
+	cancel()
+	assert.Eventually(t, func() bool {
+		return !hasRecordMetrics(t, names[0]) && !hasRecordMetrics(t, names[1])
+	}, time.Second, 10*time.Millisecond)
+}
+
+// ecsQuery returns an A query for name carrying the client subnet ip/prefix.
+func ecsQuery(name, ip string, prefix uint8) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, dns.TypeA)
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: prefix,
+		Address:       net.ParseIP(ip),
+	})
+	return msg
+}
+
+// responseSubnet returns the ECS option of a response, or nil.
+func responseSubnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, option := range opt.Option {
+		if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return ecs
+		}
+	}
+	return nil
+}
+
+func TestServeDNS_ECSScope(t *testing.T) {
+	failover := &Record{
+		Fqdn:      "app.example.com.",
+		Mode:      "failover",
+		Backends:  []BackendInterface{&Backend{Address: "192.168.1.1", Enable: true, Alive: true, Priority: 1}},
+		RecordTTL: 60,
+	}
+	geoip := &Record{
+		Fqdn: "geo.example.com.",
+		Mode: "geoip",
+		Backends: []BackendInterface{
+			&Backend{Address: "10.0.0.42", Enable: true, Alive: true, Priority: 10, Location: "eu-west"},
+			&Backend{Address: "192.168.1.42", Enable: true, Alive: true, Priority: 20, Location: "us-east"},
+		},
+		RecordTTL: 60,
+	}
+	g := &GSLB{
+		Zones: map[string]string{"example.com.": "dummy.yml"},
+		Records: map[string]map[string]*Record{"example.com.": {
+			"app.example.com.": failover,
+			"geo.example.com.": geoip,
+		}},
+		LocationMap:    map[string]string{"10.0.0.0/8": "eu-west", "10.0.0.0/24": "eu-west", "192.168.1.0/24": "us-east"},
+		UseEDNSCSubnet: true,
+	}
+
+	testCases := []struct {
+		name   string
+		fqdn   string
+		client string
+		source uint8
+		answer string
+		scope  uint8
+	}{
+		{"failover does not depend on the client", "app.example.com.", "10.0.0.0", 24, "192.168.1.1", 0},
+		{"geoip uses the most specific subnet", "geo.example.com.", "10.0.0.0", 24, "10.0.0.42", 24},
+		{"geoip scope never exceeds the source", "geo.example.com.", "192.168.1.0", 16, "192.168.1.42", 16},
+		{"geoip without a matching subnet", "geo.example.com.", "172.16.0.0", 24, "10.0.0.42", 24},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &mockResponseWriter{}
+			code, err := g.ServeDNS(context.Background(), w, ecsQuery(tc.fqdn, tc.client, tc.source))
+			assert.NoError(t, err)
+			assert.Equal(t, dns.RcodeSuccess, code)
+			assert.Len(t, w.msg.Answer, 1)
+			assert.Equal(t, tc.answer, w.msg.Answer[0].(*dns.A).A.String())
+			ecs := responseSubnet(w.msg)
+			if assert.NotNil(t, ecs) {
+				assert.Equal(t, tc.source, ecs.SourceNetmask)
+				assert.Equal(t, tc.scope, ecs.SourceScope)
+				assert.Equal(t, tc.client, ecs.Address.String())
+			}
+		})
+	}
+
+	// Answers of the next plugin are left untouched
+	g.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	})
+	w := &mockResponseWriter{}
+	_, err := g.ServeDNS(context.Background(), w, ecsQuery("other.example.com.", "10.0.0.0", 24))
+	assert.NoError(t, err)
+	if assert.NotNil(t, w.msg) {
+		assert.Nil(t, responseSubnet(w.msg))
+	}
+
+	// The client subnet is not echoed when it is not used
+	g.UseEDNSCSubnet = false
+	w = &mockResponseWriter{}
+	_, err = g.ServeDNS(context.Background(), w, ecsQuery("geo.example.com.", "10.0.0.0", 24))
+	assert.NoError(t, err)
+	assert.Nil(t, responseSubnet(w.msg))
+}
@@ -75,6 +75,9 @@ func (hc *HealthCheck) ToSpecificHealthCheck() (GenericHealthCheck, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode HTTP params: %w", err)
 		}
+		if httpCheck.LoadJSONPath != "" && httpCheck.LoadMetric != "" {
+			return nil, fmt.Errorf("load_json_path and load_metric cannot be set together")
+		}
 		return &httpCheck, nil
 
 	case ICMPType:
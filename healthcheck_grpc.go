@@ -18,6 +18,12 @@ type GRPCHealthCheck struct {
 }
 
 func (h *GRPCHealthCheck) Check() error {
+	_, err := h.check()
+	return err
+}
+
+// check runs the gRPC health check, and returns the reason of the failure (connection or protocol) with its error.
+func (h *GRPCHealthCheck) check() (string, error) {
 	addr := fmt.Sprintf("%s:%d", h.Host, h.Port)
 	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
 	defer cancel()
@@ -28,21 +34,18 @@ func (h *GRPCHealthCheck) Check() error {
 		// grpc.WithBlock(), // Not supported by grpc.NewClient, connection is lazy
 	)
 	if err != nil {
-		IncHealthcheckFailures("grpc", addr, "connection")
-		return fmt.Errorf("gRPC connection failed: %w", err)
+		return "connection", fmt.Errorf("gRPC connection failed: %w", err)
 	}
 	defer cc.Close()
 	client := healthpb.NewHealthClient(cc)
 	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: h.Service})
 	if err != nil {
-		IncHealthcheckFailures("grpc", addr, "connection")
-		return err
+		return "connection", err
 	}
 	if resp.Status != healthpb.HealthCheckResponse_SERVING {
-		IncHealthcheckFailures("grpc", addr, "protocol")
-		return fmt.Errorf("gRPC health status: %s", resp.Status.String())
+		return "protocol", fmt.Errorf("gRPC health status: %s", resp.Status.String())
 	}
-	return nil
+	return "", nil
 }
 
 func (h *GRPCHealthCheck) SetDefault() {
@@ -57,7 +60,7 @@ func (h *GRPCHealthCheck) SetDefault() {
 func (h *GRPCHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries int) bool {
 	host := h.Host
 	if host == "" && backend != nil {
-		host = backend.Address
+		host = backend.checkAddress(h)
 	}
 	check := &GRPCHealthCheck{
 		Host:    host,
@@ -65,7 +68,17 @@ func (h *GRPCHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 		Service: h.Service,
 		Timeout: h.Timeout,
 	}
-	return check.Check() == nil
+	reason, err := check.check()
+	if err != nil {
+		log.Debugf("[%s] gRPC health check failed for %s:%d: %v", fqdn, host, h.Port, err)
+		if backend != nil {
+			backend.healthcheckFailed(h, reason)
+		} else {
+			IncHealthcheckFailures(h.GetType(), host, reason)
+		}
+		return false
+	}
+	return true
 }
 
 func (h *GRPCHealthCheck) GetType() string {
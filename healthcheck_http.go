@@ -1,6 +1,7 @@
 package gslb
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -27,6 +28,8 @@ type HTTPHealthCheck struct {
 	ExpectedCode  int               `yaml:"expected_code" default:"200"`
 	ExpectedBody  string            `yaml:"expected_body" default:""`
 	SkipTLSVerify bool              `yaml:"skip_tls_verify" default:"false"`
+	LoadJSONPath  string            `yaml:"load_json_path" default:""` // Path of the backend load in a JSON body, e.g. "stats.connections"
+	LoadMetric    string            `yaml:"load_metric" default:""`    // Prometheus metric of the backend load in the body
 }
 
 func (h *HTTPHealthCheck) SetDefault() {
@@ -80,17 +83,15 @@ func createHTTPClient(enableTLS bool, skipTLSVerify bool, timeout time.Duration)
 func (h *HTTPHealthCheck) retryHealthCheck(client *http.Client, req *http.Request, backend *Backend, fqdn string, maxRetries int) (*http.Response, error) {
 	var resp *http.Response
 	var err error
-	typeStr := h.GetType()
-	address := backend.Address
 	for retry := 0; retry <= maxRetries; retry++ {
 		resp, err = client.Do(req)
 		if err == nil && resp.StatusCode == h.ExpectedCode {
 			// Check the body if expected
 			if h.ExpectedBody != "" {
-				if err := h.checkExpectedBody(resp.Body, fqdn); err != nil {
+				if err := h.checkExpectedBody(resp, fqdn); err != nil {
 					log.Debugf("[%s] HTTP healthcheck body mismatch: %v", fqdn, err)
 					if retry == maxRetries {
-						IncHealthcheckFailures(typeStr, address, "protocol")
+						backend.healthcheckFailed(h, "protocol")
 						return nil, err
 					}
 					continue
@@ -103,13 +104,13 @@ func (h *HTTPHealthCheck) retryHealthCheck(client *http.Client, req *http.Reques
 		if err != nil {
 			log.Debugf("[%s] HTTP healthcheck failed (retries=%d/%d): [backend=%s:%d uri:%s method:%s host:%s] %v", fqdn, retry, maxRetries, backend.Address, h.Port, h.URI, h.Method, h.Host, err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return nil, err
 			}
 		} else {
 			log.Debugf("[%s] HTTP healthcheck failed (retries=%d/%d): [backend=%s:%d uri:%s method:%s host:%s] unexpected status code: got %d, want %d", fqdn, retry, maxRetries, backend.Address, h.Port, h.URI, h.Method, h.Host, resp.StatusCode, h.ExpectedCode)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "protocol")
+				backend.healthcheckFailed(h, "protocol")
 				return nil, fmt.Errorf("[%s] HTTP health check failed after %d retries", fqdn, maxRetries)
 			}
 		}
@@ -118,13 +119,14 @@ func (h *HTTPHealthCheck) retryHealthCheck(client *http.Client, req *http.Reques
 }
 
 // checkExpectedBody reads and checks the response body against the expected body.
-func (h *HTTPHealthCheck) checkExpectedBody(body io.ReadCloser, fqdn string) error {
-	defer body.Close()
-
-	bodyBytes, err := io.ReadAll(body)
+// The body can be read again afterwards.
+func (h *HTTPHealthCheck) checkExpectedBody(resp *http.Response, fqdn string) error {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
 	if err != nil {
 		return fmt.Errorf("[%s] failed to read response body: %w", fqdn, err)
 	}
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 
 	if matched, err := regexp.MatchString(h.ExpectedBody, string(bodyBytes)); err != nil {
 		return fmt.Errorf("[%s] invalid regex for expected body: %w", fqdn, err)
@@ -134,6 +136,19 @@ func (h *HTTPHealthCheck) checkExpectedBody(body io.ReadCloser, fqdn string) err
 	return nil
 }
 
+// dialAddress makes the client connect to the given address whatever the host of the request URL.
+func dialAddress(client *http.Client, address string) {
+	transport := client.Transport.(*http.Transport)
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, net.JoinHostPort(address, port))
+	}
+}
+
 // PerformCheck implements the HealthCheck interface for HTTP health checks
 func (h *HTTPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries int) bool {
 	typeStr := h.GetType()
@@ -155,11 +170,15 @@ func (h *HTTPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 	t, err := time.ParseDuration(h.Timeout)
 	if err != nil {
 		log.Errorf("[%s] invalid timeout format: %v", fqdn, err)
-		IncHealthcheckFailures(typeStr, address, "timeout")
+		backend.healthcheckFailed(h, "timeout")
 		return false
 	}
 
 	client := createHTTPClient(h.EnableTLS, h.SkipTLSVerify, t)
+	// The URL keeps the hostname of a CNAME backend so Host and TLS SNI match a real client
+	if target := backend.checkAddress(h); target != backend.Address {
+		dialAddress(client, target)
+	}
 
 	// Create HTTP request
 	ctx, cancel := context.WithTimeout(context.Background(), t)
@@ -168,7 +187,7 @@ func (h *HTTPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 	req, err := http.NewRequestWithContext(ctx, h.Method, url, nil)
 	if err != nil {
 		log.Debugf("[%s] HTTP healthcheck failed: [backend=%s:%d scheme:%s uri:%s method:%s host:%s] error to create http request: %v", fqdn, backend.Address, h.Port, scheme, h.URI, h.Method, h.Host, err)
-		IncHealthcheckFailures(typeStr, address, "other")
+		backend.healthcheckFailed(h, "other")
 		return false
 	}
 	req.Host = h.Host
@@ -185,12 +204,29 @@ func (h *HTTPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 
 	// Log successful health check
 	defer resp.Body.Close()
+	if h.LoadJSONPath != "" || h.LoadMetric != "" {
+		h.reportLoad(resp.Body, backend, fqdn)
+	}
 
 	log.Debugf("[%s] HTTP healthcheck success [backend=%s:%d scheme:%s uri:%s method:%s host:%s]", fqdn, backend.Address, h.Port, scheme, h.URI, h.Method, h.Host)
 	result = true
 	return true
 }
 
+// reportLoad reports the backend load found in the response body. A missing load does not fail the check,
+// the backend is then ranked after the ones reporting their load.
+func (h *HTTPHealthCheck) reportLoad(body io.Reader, backend *Backend, fqdn string) {
+	bodyBytes, err := io.ReadAll(body)
+	if err == nil {
+		var load float64
+		if load, err = extractLoad(bodyBytes, h.LoadJSONPath, h.LoadMetric); err == nil {
+			backend.reportLoad(load)
+			return
+		}
+	}
+	log.Debugf("[%s] HTTP healthcheck load not found [backend=%s:%d uri:%s]: %v", fqdn, backend.Address, h.Port, h.URI, err)
+}
+
 // Equals compares two HTTPHealthCheck objects for equality.
 func (h *HTTPHealthCheck) Equals(other GenericHealthCheck) bool {
 	otherHTTP, ok := other.(*HTTPHealthCheck)
@@ -208,6 +244,8 @@ func (h *HTTPHealthCheck) Equals(other GenericHealthCheck) bool {
 		h.ExpectedCode != otherHTTP.ExpectedCode ||
 		h.ExpectedBody != otherHTTP.ExpectedBody ||
 		h.SkipTLSVerify != otherHTTP.SkipTLSVerify ||
+		h.LoadJSONPath != otherHTTP.LoadJSONPath ||
+		h.LoadMetric != otherHTTP.LoadMetric ||
 		len(h.Headers) != len(otherHTTP.Headers) {
 		return false
 	}
@@ -1,11 +1,14 @@
 package gslb
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -164,3 +167,71 @@ func TestHTTPHealthCheck_Equals(t *testing.T) {
 	// Assert that hc1 and hc3 are not equal
 	assert.False(t, hc1.Equals(hc3))
 }
+
+func TestHTTPHealthCheck_Load(t *testing.T) {
+	body := `{"connections": {"active": 42}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	// The body is read for the expected body and the load
+	hc := &HTTPHealthCheck{
+		Port:         server.Listener.Addr().(*net.TCPAddr).Port,
+		URI:          "/status",
+		Method:       "GET",
+		Timeout:      "2s",
+		ExpectedCode: 200,
+		ExpectedBody: "connections",
+		LoadJSONPath: "connections.active",
+	}
+	backend := &Backend{
+		Fqdn:         "load.example.com.",
+		Address:      server.Listener.Addr().(*net.TCPAddr).IP.String(),
+		Enable:       true,
+		HealthChecks: []GenericHealthCheck{hc},
+	}
+	backend.runHealthChecks(0, 2*time.Second)
+	assert.True(t, backend.Alive)
+	load, reported := backend.GetLoad()
+	assert.True(t, reported)
+	assert.Equal(t, 42.0, load)
+
+	// A load that cannot be read does not fail the check, and is no longer reported
+	body = `{"connections": {}}`
+	backend.runHealthChecks(0, 2*time.Second)
+	assert.True(t, backend.Alive)
+	_, reported = backend.GetLoad()
+	assert.False(t, reported)
+}
+
+func TestHTTPHealthCheck_CNAMEBackend(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	defer func(orig func(context.Context, string) ([]net.IPAddr, error)) { lookupIPAddr = orig }(lookupIPAddr)
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+
+	// The check connects to the resolved address and keeps the hostname in the request
+	backend := &Backend{
+		Fqdn:         "app.example.com.",
+		Address:      "lb-eu.cloudprovider.invalid",
+		Enable:       true,
+		HealthChecks: []GenericHealthCheck{&HTTPHealthCheck{Port: port, URI: "/", Method: "GET", Timeout: "2s", ExpectedCode: 200}},
+	}
+	backend.runHealthChecks(0, 2*time.Second)
+	assert.True(t, backend.Alive)
+	assert.Equal(t, fmt.Sprintf("lb-eu.cloudprovider.invalid:%d", port), host)
+}
+
+func TestHealthCheck_LoadParamsExclusive(t *testing.T) {
+	hc := &HealthCheck{Type: "http", Params: map[string]interface{}{"load_json_path": "connections", "load_metric": "connections"}}
+	_, err := hc.ToSpecificHealthCheck()
+	assert.ErrorContains(t, err, "cannot be set together")
+}
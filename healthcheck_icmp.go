@@ -36,16 +36,16 @@ func (h *ICMPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 	timeout, err := time.ParseDuration(h.Timeout)
 	if err != nil {
 		log.Errorf("[%s] invalid timeout format: %v", fqdn, err)
-		IncHealthcheckFailures(typeStr, address, "timeout")
+		backend.healthcheckFailed(h, "timeout")
 		return false
 	}
 
 	for retry := 0; retry <= maxRetries; retry++ {
-		pinger, err := createPinger(backend.Address, h.Count, timeout)
+		pinger, err := createPinger(backend.checkAddress(h), h.Count, timeout)
 		if err != nil {
 			log.Errorf("[%s] ICMP health check failed to initialize pinger: %v", fqdn, err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return false
 			}
 			continue
@@ -57,7 +57,7 @@ func (h *ICMPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 		if err != nil {
 			log.Debugf("[%s] ICMP health check failed: %v", fqdn, err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return false
 			}
 			continue
@@ -71,7 +71,7 @@ func (h *ICMPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 		}
 	}
 
-	IncHealthcheckFailures(typeStr, address, "other")
+	backend.healthcheckFailed(h, "other")
 	return false
 }
 
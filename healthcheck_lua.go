@@ -9,7 +9,6 @@ import (
 	"net"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"crypto/tls"
@@ -61,10 +60,14 @@ func (l *LuaHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 	L.SetGlobal("json_decode", L.NewFunction(luaJSONDecode))
 	L.SetGlobal("metric_get", L.NewFunction(luaMetricGet))
 	L.SetGlobal("ssh_exec", L.NewFunction(luaSSHExec))
+	L.SetGlobal("set_load", L.NewFunction(func(l *gopherlua.LState) int {
+		backend.reportLoad(float64(l.CheckNumber(1)))
+		return 0
+	}))
 
 	// Inject backend table
 	backendTable := L.NewTable()
-	L.SetField(backendTable, "address", gopherlua.LString(backend.Address))
+	L.SetField(backendTable, "address", gopherlua.LString(backend.checkAddress(l)))
 	L.SetField(backendTable, "priority", gopherlua.LNumber(backend.Priority))
 	L.SetGlobal("backend", backendTable)
 
@@ -155,19 +158,13 @@ func luaMetricGet(l *gopherlua.LState) int {
 		l.Push(gopherlua.LNil)
 		return 1
 	}
-	lines := strings.Split(string(body), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, metric+" ") || strings.HasPrefix(line, metric+"{") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				if v, err := strconv.ParseFloat(fields[len(fields)-1], 64); err == nil {
-					l.Push(gopherlua.LNumber(v))
-					return 1
-				}
-				l.Push(gopherlua.LString(fields[len(fields)-1]))
-				return 1
-			}
+	if value, ok := prometheusMetricValue(string(body), metric); ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			l.Push(gopherlua.LNumber(v))
+			return 1
 		}
+		l.Push(gopherlua.LString(value))
+		return 1
 	}
 	l.Push(gopherlua.LNil)
 	return 1
@@ -98,6 +98,18 @@ func TestLuaHealthCheck_MetricGet(t *testing.T) {
 	}
 }
 
+func TestLuaHealthCheck_SetLoad(t *testing.T) {
+	check := &LuaHealthCheck{
+		Script:  `set_load(12.5) return true`,
+		Timeout: 2 * time.Second,
+	}
+	backend := &Backend{Fqdn: "fqdn.test.", Address: "127.0.0.1", Enable: true, HealthChecks: []GenericHealthCheck{check}}
+	backend.runHealthChecks(0, 2*time.Second)
+	if load, reported := backend.GetLoad(); !reported || load != 12.5 {
+		t.Errorf("Expected Lua healthcheck to report a load of 12.5, got %v (reported=%v)", load, reported)
+	}
+}
+
 func TestLuaHealthCheck_HttpGet_Simple(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"status":"green"}`))
@@ -49,7 +49,7 @@ func (h *MySQLHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetrie
 	timeout, err := time.ParseDuration(h.Timeout)
 	if err != nil {
 		log.Errorf("[mysql] invalid timeout format: %v", err)
-		IncHealthcheckFailures(typeStr, address, "timeout")
+		backend.healthcheckFailed(h, "timeout")
 		return false
 	}
 
@@ -58,7 +58,7 @@ func (h *MySQLHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetrie
 		if err != nil {
 			log.Debugf("[mysql] connection failed: %v", err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return false
 			}
 			continue
@@ -72,7 +72,7 @@ func (h *MySQLHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetrie
 		if pingErr != nil {
 			log.Debugf("[mysql] ping failed: %v", pingErr)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return false
 			}
 			continue
@@ -84,7 +84,7 @@ func (h *MySQLHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetrie
 		if err := row.Scan(&dummy); err != nil {
 			log.Debugf("[mysql] query failed: %v", err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "protocol")
+				backend.healthcheckFailed(h, "protocol")
 				return false
 			}
 			continue
@@ -93,7 +93,7 @@ func (h *MySQLHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetrie
 		return true
 	}
 
-	IncHealthcheckFailures(typeStr, address, "other")
+	backend.healthcheckFailed(h, "other")
 	return false
 }
 
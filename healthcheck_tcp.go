@@ -38,11 +38,11 @@ func (h *TCPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 	timeout, err := time.ParseDuration(h.Timeout)
 	if err != nil {
 		log.Errorf("[%s] invalid timeout format: %v", fqdn, err)
-		IncHealthcheckFailures(typeStr, address, "timeout")
+		backend.healthcheckFailed(h, "timeout")
 		return false
 	}
 
-	addressPort := net.JoinHostPort(backend.Address, strconv.Itoa(h.Port))
+	addressPort := net.JoinHostPort(backend.checkAddress(h), strconv.Itoa(h.Port))
 	for retry := 0; retry <= maxRetries; retry++ {
 		log.Debugf("[%s] Attempting TCP health check on %s", fqdn, addressPort)
 
@@ -50,7 +50,7 @@ func (h *TCPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 		if err != nil {
 			log.Debugf("[%s] TCP health check failed (retries=%d/%d): %v", fqdn, retry, maxRetries, err)
 			if retry == maxRetries {
-				IncHealthcheckFailures(typeStr, address, "connection")
+				backend.healthcheckFailed(h, "connection")
 				return false
 			}
 			continue
@@ -63,7 +63,7 @@ func (h *TCPHealthCheck) PerformCheck(backend *Backend, fqdn string, maxRetries
 		return true
 	}
 
-	IncHealthcheckFailures(typeStr, address, "other")
+	backend.healthcheckFailed(h, "other")
 	return false
 }
 
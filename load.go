@@ -0,0 +1,102 @@
+package gslb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// extractLoad returns the load reported by a backend in a health check response body: the number at
+// a dot-separated path of a JSON document, or the value of a metric in the Prometheus text format.
+func extractLoad(body []byte, jsonPath, metric string) (float64, error) {
+	if jsonPath != "" {
+		return jsonPathNumber(body, jsonPath)
+	}
+	value, ok := prometheusMetricValue(string(body), metric)
+	if !ok {
+		return 0, fmt.Errorf("metric %s not found", metric)
+	}
+	load, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("metric %s is not a number: %s", metric, value)
+	}
+	return load, nil
+}
+
+// jsonPathNumber returns the number at a dot-separated path of a JSON document, e.g. "stats.connections".
+// Numeric path elements index arrays.
+func jsonPathNumber(body []byte, path string) (float64, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return 0, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return 0, fmt.Errorf("no element %s at %s", key, path)
+			}
+			value = node[i]
+		default:
+			value = nil
+		}
+		if value == nil {
+			return 0, fmt.Errorf("no value at %s", path)
+		}
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		// Some endpoints quote their numbers
+		if load, err := strconv.ParseFloat(v, 64); err == nil {
+			return load, nil
+		}
+	}
+	return 0, fmt.Errorf("value at %s is not a number: %v", path, value)
+}
+
+// prometheusMetricValue returns the value of the first sample of a metric in the Prometheus text format:
+// the first field after the metric name and its label set, followed by an optional timestamp.
+func prometheusMetricValue(body, metric string) (string, bool) {
+	for _, line := range strings.Split(body, "\n") {
+		rest, ok := strings.CutPrefix(line, metric)
+		if !ok || rest == "" {
+			continue
+		}
+		if rest[0] == '{' {
+			end := labelSetEnd(rest)
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		} else if rest[0] != ' ' && rest[0] != '\t' {
+			continue // Another metric sharing the prefix
+		}
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+// labelSetEnd returns the index of the brace closing the label set at the start of s, or -1.
+// Label values are quoted and may contain braces, spaces and escaped quotes.
+func labelSetEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,53 @@
+package gslb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractLoad(t *testing.T) {
+	jsonBody := []byte(`{"connections": {"active": 42, "limit": "100"}, "workers": [{"busy": 3}, {"busy": 5}], "status": "ok"}`)
+	metricsBody := []byte("# HELP nginx_connections_active Active connections\n" +
+		"nginx_connections_active 17\n" +
+		"process_cpu_usage{core=\"0\"} 0.75\n" +
+		"process_state{state=\"up\"} running\n" +
+		"nginx_connections_active_total 99\n" +
+		"queue_depth{queue=\"a b\",path=\"{x} \\\"y\\\"\"} 7 1712345678000\n" +
+		"http_inflight 3 1712345678000\n")
+
+	testCases := []struct {
+		name     string
+		body     []byte
+		jsonPath string
+		metric   string
+		expected float64
+		wantErr  bool
+	}{
+		{"json nested number", jsonBody, "connections.active", "", 42, false},
+		{"json quoted number", jsonBody, "connections.limit", "", 100, false},
+		{"json array index", jsonBody, "workers.1.busy", "", 5, false},
+		{"json missing key", jsonBody, "connections.idle", "", 0, true},
+		{"json index out of range", jsonBody, "workers.2.busy", "", 0, true},
+		{"json not a number", jsonBody, "status", "", 0, true},
+		{"json invalid body", metricsBody, "connections.active", "", 0, true},
+		{"metric without labels", metricsBody, "", "nginx_connections_active", 17, false},
+		{"metric with labels", metricsBody, "", "process_cpu_usage", 0.75, false},
+		{"metric with a timestamp", metricsBody, "", "http_inflight", 3, false},
+		{"metric with spaces, braces and quotes in labels", metricsBody, "", "queue_depth", 7, false},
+		{"metric name prefix of another", metricsBody, "", "nginx_connections", 0, true},
+		{"metric missing", metricsBody, "", "nginx_connections_waiting", 0, true},
+		{"metric not a number", metricsBody, "", "process_state", 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			load, err := extractLoad(tc.body, tc.jsonPath, tc.metric)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, load)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package gslb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maintenanceLookahead bounds the search of the next occurrence of a recurring window.
+// It spans a leap day, for recurrences on February 29.
+const maintenanceLookahead = 5 * 366 * 24 * time.Hour
+
+// MaintenanceWindow is a period during which a backend is treated as disabled.
+// With a recurrence, the window repeats at every match of the cron expression at or after
+// Start, and each occurrence lasts End - Start.
+type MaintenanceWindow struct {
+	ID         string    `json:"id,omitempty"` // Set for windows created through the API
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Recurrence string    `json:"recurrence,omitempty"` // Cron expression: minute hour day-of-month month day-of-week
+	Reason     string    `json:"reason,omitempty"`
+	schedule   *cronSchedule
+}
+
+// UnmarshalYAML parses RFC3339 start and end times and validates the recurrence.
+func (w *MaintenanceWindow) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Start      string `yaml:"start"`
+		End        string `yaml:"end"`
+		Recurrence string `yaml:"recurrence"`
+		Reason     string `yaml:"reason"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	start, err := time.Parse(time.RFC3339, raw.Start)
+	if err != nil {
+		return fmt.Errorf("maintenance start must be a RFC3339 time: %v", raw.Start)
+	}
+	end, err := time.Parse(time.RFC3339, raw.End)
+	if err != nil {
+		return fmt.Errorf("maintenance end must be a RFC3339 time: %v", raw.End)
+	}
+	w.Start = start
+	w.End = end
+	w.Recurrence = raw.Recurrence
+	w.Reason = raw.Reason
+	return w.validate()
+}
+
+// validate checks the window bounds and compiles its recurrence.
+func (w *MaintenanceWindow) validate() error {
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("maintenance end must be after start")
+	}
+	w.schedule = nil
+	if w.Recurrence != "" {
+		schedule, err := parseCron(w.Recurrence)
+		if err != nil {
+			return err
+		}
+		w.schedule = schedule
+	}
+	return nil
+}
+
+// state reports whether the window is active at now, and when this may change (zero if never).
+func (w *MaintenanceWindow) state(now time.Time) (bool, time.Time) {
+	if w.schedule == nil {
+		switch {
+		case now.Before(w.Start):
+			return false, w.Start
+		case now.Before(w.End):
+			return true, w.End
+		default:
+			return false, time.Time{}
+		}
+	}
+
+	// Cron expressions are evaluated in the time zone of the start time
+	duration := w.End.Sub(w.Start)
+	now = now.In(w.Start.Location())
+	first := w.Start.Truncate(time.Minute)
+
+	// Latest occurrence still running
+	limit := first
+	if now.Add(-duration).After(limit) {
+		limit = now.Add(-duration)
+	}
+	if t := w.schedule.prev(now, limit); !t.IsZero() && t.After(now.Add(-duration)) {
+		return true, t.Add(duration)
+	}
+
+	// Next occurrence
+	t := now.Truncate(time.Minute).Add(time.Minute)
+	if t.Before(first) {
+		t = first
+	}
+	return false, w.schedule.next(t, t.Add(maintenanceLookahead))
+}
+
+// expired reports whether a window without recurrence is over.
+func (w *MaintenanceWindow) expired(now time.Time) bool {
+	return w.schedule == nil && !now.Before(w.End)
+}
+
+// maintenanceState evaluates a set of windows: active if any window is active, and the earliest change time.
+func maintenanceState(windows []MaintenanceWindow, now time.Time) (bool, time.Time) {
+	active := false
+	var next time.Time
+	for i := range windows {
+		a, change := windows[i].state(now)
+		active = active || a
+		if !change.IsZero() && (next.IsZero() || change.Before(next)) {
+			next = change
+		}
+	}
+	return active, next
+}
+
+// formatMaintenance summarizes the maintenance state of a backend for the TXT debug answer.
+func formatMaintenance(windows []MaintenanceWindow, now time.Time) string {
+	active, next := maintenanceState(windows, now)
+	switch {
+	case active:
+		return fmt.Sprintf("Maintenance: active until %s", next.Format(time.RFC3339))
+	case !next.IsZero():
+		return fmt.Sprintf("Maintenance: next %s", next.Format(time.RFC3339))
+	default:
+		return "Maintenance: none scheduled"
+	}
+}
+
+// newMaintenanceID returns a random identifier for a window created through the API.
+func newMaintenanceID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cronSchedule is a compiled 5-field cron expression, one bit per allowed value.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week", with *, lists, ranges and steps.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid recurrence %q: expected 5 cron fields", expr)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence %q: %w", expr, err)
+		}
+		masks[i] = mask
+	}
+	// Sunday is both 0 and 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+	return &cronSchedule{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], s
+		}
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// next returns the first minute at or after t matching the schedule, or zero if none is before limit.
+// Each field that does not match skips to the start of its next value instead of stepping minute by minute.
+func (c *cronSchedule) next(t, limit time.Time) time.Time {
+	loc := t.Location()
+	for t = t.Truncate(time.Minute); t.Before(limit); {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// prev returns the last minute at or before t matching the schedule, or zero if none is at or after limit.
+func (c *cronSchedule) prev(t, limit time.Time) time.Time {
+	loc := t.Location()
+	for t = t.Truncate(time.Minute); !t.Before(limit); {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(-time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matches reports whether the minute of t matches the schedule.
+// As in cron, when both day fields are restricted a day matching either one matches.
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	return c.dayMatches(t)
+}
+
+// dayMatches reports whether the day of t matches the day-of-month and day-of-week fields.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
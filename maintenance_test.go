@@ -0,0 +1,196 @@
+package gslb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 2 * * 0", false},
+		{"*/15 * * * *", false},
+		{"0 1-5 1,15 * 1-5", false},
+		{"0 2 * * 7", false},
+		{"0 2 * *", true},
+		{"60 * * * *", true},
+		{"0 2 * * mon", true},
+		{"*/0 * * * *", true},
+		{"0 5-1 * * *", true},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.expr)
+		if tt.wantErr {
+			assert.Error(t, err, tt.expr)
+		} else {
+			assert.NoError(t, err, tt.expr)
+		}
+	}
+}
+
+func TestCronSchedule_Matches(t *testing.T) {
+	// Sunday 7 matches Sunday 0
+	c, err := parseCron("30 2 * * 7")
+	assert.NoError(t, err)
+	assert.True(t, c.matches(time.Date(2025, 6, 1, 2, 30, 0, 0, time.UTC))) // Sunday
+	assert.False(t, c.matches(time.Date(2025, 6, 2, 2, 30, 0, 0, time.UTC)))
+	assert.False(t, c.matches(time.Date(2025, 6, 1, 2, 31, 0, 0, time.UTC)))
+
+	// Both day fields restricted: either one matches
+	c, err = parseCron("0 0 15 * 1")
+	assert.NoError(t, err)
+	assert.True(t, c.matches(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC))) // Sunday the 15th
+	assert.True(t, c.matches(time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)))  // Monday
+	assert.False(t, c.matches(time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestCronSchedule_NextPrev(t *testing.T) {
+	// Same results as a minute by minute search
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"*/15 * * * *", "30 2 * * 0", "0 0 15 * 1", "5 4 1 1,7 *"} {
+		c, err := parseCron(expr)
+		assert.NoError(t, err)
+		for _, now := range []time.Time{from, from.Add(90*time.Minute + 30*time.Second), from.Add(40 * 24 * time.Hour)} {
+			expected := now.Truncate(time.Minute)
+			for !c.matches(expected) {
+				expected = expected.Add(time.Minute)
+			}
+			assert.Equal(t, expected, c.next(now, now.Add(maintenanceLookahead)), expr)
+			expected = now.Truncate(time.Minute)
+			for !c.matches(expected) {
+				expected = expected.Add(-time.Minute)
+			}
+			assert.Equal(t, expected, c.prev(now, now.Add(-maintenanceLookahead)), expr)
+		}
+	}
+
+	// A leap day is found years ahead, and a day that never exists is not
+	c, err := parseCron("0 3 29 2 *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2028, 2, 29, 3, 0, 0, 0, time.UTC), c.next(from, from.Add(maintenanceLookahead)))
+	assert.Equal(t, time.Date(2024, 2, 29, 3, 0, 0, 0, time.UTC), c.prev(from, from.Add(-maintenanceLookahead)))
+	c, err = parseCron("0 0 31 2 *")
+	assert.NoError(t, err)
+	assert.True(t, c.next(from, from.Add(maintenanceLookahead)).IsZero())
+}
+
+func TestMaintenanceWindow_State(t *testing.T) {
+	start := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	w := MaintenanceWindow{Start: start, End: start.Add(time.Hour)}
+	assert.NoError(t, w.validate())
+
+	active, next := w.state(start.Add(-time.Minute))
+	assert.False(t, active)
+	assert.Equal(t, start, next)
+	active, next = w.state(start.Add(30 * time.Minute))
+	assert.True(t, active)
+	assert.Equal(t, start.Add(time.Hour), next)
+	active, next = w.state(start.Add(2 * time.Hour))
+	assert.False(t, active)
+	assert.True(t, next.IsZero())
+	assert.True(t, w.expired(start.Add(2*time.Hour)))
+}
+
+func TestMaintenanceWindow_State_Recurring(t *testing.T) {
+	// Every Sunday from 02:00 to 03:00, starting on Sunday 2025-06-01
+	start := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	w := MaintenanceWindow{Start: start, End: start.Add(time.Hour), Recurrence: "0 2 * * 0"}
+	assert.NoError(t, w.validate())
+
+	// Before the first occurrence
+	active, next := w.state(start.Add(-24 * time.Hour))
+	assert.False(t, active)
+	assert.Equal(t, start, next)
+
+	// During the second occurrence
+	active, next = w.state(start.Add(7*24*time.Hour + 15*time.Minute))
+	assert.True(t, active)
+	assert.Equal(t, start.Add(7*24*time.Hour+time.Hour), next)
+
+	// Between occurrences
+	active, next = w.state(start.Add(2 * 24 * time.Hour))
+	assert.False(t, active)
+	assert.Equal(t, start.Add(7*24*time.Hour), next)
+	assert.False(t, w.expired(start.Add(365*24*time.Hour)))
+}
+
+func TestMaintenanceWindow_UnmarshalYAML(t *testing.T) {
+	var w MaintenanceWindow
+	err := yaml.Unmarshal([]byte(`
+start: "2025-06-01T02:00:00Z"
+end: "2025-06-01T03:00:00Z"
+recurrence: "0 2 * * 0"
+reason: weekly patching
+`), &w)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC), w.Start)
+	assert.Equal(t, "weekly patching", w.Reason)
+	assert.NotNil(t, w.schedule)
+
+	assert.Error(t, yaml.Unmarshal([]byte(`{start: "tomorrow", end: "2025-06-01T03:00:00Z"}`), &w))
+	assert.Error(t, yaml.Unmarshal([]byte(`{start: "2025-06-01T03:00:00Z", end: "2025-06-01T02:00:00Z"}`), &w))
+	assert.Error(t, yaml.Unmarshal([]byte(`{start: "2025-06-01T02:00:00Z", end: "2025-06-01T03:00:00Z", recurrence: "weekly"}`), &w))
+}
+
+func TestBackend_InMaintenance(t *testing.T) {
+	now := time.Now()
+	backend := &Backend{
+		Fqdn:    "app.example.com.",
+		Address: "10.0.0.1",
+		Enable:  true,
+		Alive:   true,
+		Maintenance: []MaintenanceWindow{
+			{Start: now.Add(-time.Minute), End: now.Add(time.Hour)},
+		},
+	}
+	assert.True(t, backend.InMaintenance())
+	assert.False(t, backend.IsHealthy())
+
+	backend.Maintenance = []MaintenanceWindow{{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}}
+	backend.maintenanceChecked = false
+	assert.False(t, backend.InMaintenance())
+	assert.True(t, backend.IsHealthy())
+}
+
+func TestGSLB_Maintenance_Runtime(t *testing.T) {
+	now := time.Now()
+	backend := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true, Alive: true}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}}},
+	}}
+
+	window := MaintenanceWindow{ID: "abc", Start: now.Add(-time.Minute), End: now.Add(time.Hour)}
+	assert.NoError(t, window.validate())
+	g.addMaintenance("app.example.com.", backend, window)
+	assert.True(t, backend.InMaintenance())
+	assert.Len(t, g.listMaintenance(), 1)
+
+	// The backend keeps its own copy of the windows
+	g.Maintenance[overrideKey("app.example.com.", "10.0.0.1")][0].Reason = "changed"
+	assert.Empty(t, backend.GetMaintenanceWindows()[0].Reason)
+
+	// Only the backend of the removed window is updated
+	other := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.2", Enable: true, Alive: true}
+	g.Records["example.com."]["app.example.com."].Backends = append(g.Records["example.com."]["app.example.com."].Backends, other)
+	g.addMaintenance("app.example.com.", other, MaintenanceWindow{ID: "def", Start: now.Add(-time.Minute), End: now.Add(time.Hour)})
+	other.setRuntimeMaintenance(nil)
+
+	assert.True(t, g.removeMaintenance("abc"))
+	assert.False(t, backend.InMaintenance())
+	assert.False(t, g.removeMaintenance("abc"))
+	assert.Empty(t, other.GetMaintenanceWindows())
+	assert.Len(t, g.listMaintenance(), 1)
+}
+
+func TestFormatMaintenance(t *testing.T) {
+	start := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	windows := []MaintenanceWindow{{Start: start, End: start.Add(time.Hour)}}
+	assert.Equal(t, "Maintenance: next 2025-06-01T02:00:00Z", formatMaintenance(windows, start.Add(-time.Hour)))
+	assert.Equal(t, "Maintenance: active until 2025-06-01T03:00:00Z", formatMaintenance(windows, start))
+	assert.Equal(t, "Maintenance: none scheduled", formatMaintenance(windows, start.Add(2*time.Hour)))
+}
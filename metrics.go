@@ -127,10 +127,52 @@ var (
 	backendHealthcheckStatus = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "gslb_backend_healthcheck_status",
-			Help: "Healthcheck status per backend and type (2 = disabled, 1 = success, 0 = fail).",
+			Help: "Healthcheck status per backend and type (3 = in maintenance, 2 = disabled, 1 = success, 0 = fail).",
 		},
 		[]string{"name", "address", "type"},
 	)
+	recordFallback = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gslb_record_fallback_total",
+			Help: "Total number of responses built from the fallback policy because no backend was healthy, labeled by record name and policy.",
+		},
+		[]string{"name", "policy"},
+	)
+	backendConsecutiveChecks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gslb_backend_consecutive_checks",
+			Help: "Current number of consecutive healthcheck results per backend (result = success or failure).",
+		},
+		[]string{"name", "address", "result"},
+	)
+	backendMaintenance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gslb_backend_maintenance",
+			Help: "Maintenance status per backend (1 = in a maintenance window, 0 = not).",
+		},
+		[]string{"name", "address"},
+	)
+	backendLoad = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gslb_backend_load",
+			Help: "Load reported by the health checks per backend, used by the least_loaded mode.",
+		},
+		[]string{"name", "address"},
+	)
+	notificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gslb_notifications_total",
+			Help: "Total number of notifications sent to the notifiers, labeled by notifier and result (success or failure).",
+		},
+		[]string{"notifier", "result"},
+	)
+	apiAuthDenied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gslb_api_auth_denied_total",
+			Help: "Total number of denied API requests, labeled by reason (unauthenticated or forbidden).",
+		},
+		[]string{"reason"},
+	)
 )
 
 var metricsOnce sync.Once
@@ -153,9 +195,58 @@ func RegisterMetrics() {
 		prometheus.MustRegister(recordHealthStatus)
 		prometheus.MustRegister(backendHealthStatus)
 		prometheus.MustRegister(backendHealthcheckStatus)
+		prometheus.MustRegister(recordFallback)
+		prometheus.MustRegister(backendConsecutiveChecks)
+		prometheus.MustRegister(backendMaintenance)
+		prometheus.MustRegister(backendLoad)
+		prometheus.MustRegister(notificationsTotal)
+		prometheus.MustRegister(apiAuthDenied)
 	})
 }
 
+// monitoredRecords counts the plugin instances running the health checks of each record. After a
+// Corefile reload, the old instance stops while the new one already monitors the same records.
+var monitoredRecords = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// trackRecordMetrics registers an instance running the health checks of a record.
+func trackRecordMetrics(name string) {
+	monitoredRecords.Lock()
+	defer monitoredRecords.Unlock()
+	monitoredRecords.count[name]++
+}
+
+// releaseRecordMetrics unregisters an instance running the health checks of a record,
+// and deletes the metric series of the record once no instance monitors it.
+func releaseRecordMetrics(name string) {
+	monitoredRecords.Lock()
+	defer monitoredRecords.Unlock()
+	if monitoredRecords.count[name]--; monitoredRecords.count[name] > 0 {
+		return
+	}
+	delete(monitoredRecords.count, name)
+	DeleteRecordMetrics(name)
+}
+
+// DeleteRecordMetrics deletes the metric series labeled with a record name.
+func DeleteRecordMetrics(name string) {
+	labels := prometheus.Labels{"name": name}
+	healthcheckTotal.DeletePartialMatch(labels)
+	recordResolutions.DeletePartialMatch(labels)
+	activeBackends.DeletePartialMatch(labels)
+	backendSelected.DeletePartialMatch(labels)
+	recordResolutionDuration.DeletePartialMatch(labels)
+	recordHealthStatus.DeletePartialMatch(labels)
+	backendHealthStatus.DeletePartialMatch(labels)
+	backendHealthcheckStatus.DeletePartialMatch(labels)
+	recordFallback.DeletePartialMatch(labels)
+	backendConsecutiveChecks.DeletePartialMatch(labels)
+	backendMaintenance.DeletePartialMatch(labels)
+	backendLoad.DeletePartialMatch(labels)
+}
+
 func IncHealthcheckTotal(name, typ, address, result string) {
 	healthcheckTotal.WithLabelValues(name, typ, address, result).Inc()
 }
@@ -213,6 +304,35 @@ func SetBackendHealthcheckStatus(name, address, typeStr string, value float64) {
 	backendHealthcheckStatus.WithLabelValues(name, address, typeStr).Set(value)
 }
 
+func IncRecordFallback(name, policy string) {
+	recordFallback.WithLabelValues(name, policy).Inc()
+}
+
+func SetBackendConsecutiveChecks(name, address string, success, failure int) {
+	backendConsecutiveChecks.WithLabelValues(name, address, "success").Set(float64(success))
+	backendConsecutiveChecks.WithLabelValues(name, address, "failure").Set(float64(failure))
+}
+
+func SetBackendMaintenance(name, address string, value float64) {
+	backendMaintenance.WithLabelValues(name, address).Set(value)
+}
+
+func SetBackendLoad(name, address string, value float64) {
+	backendLoad.WithLabelValues(name, address).Set(value)
+}
+
+func DeleteBackendLoad(name, address string) {
+	backendLoad.DeleteLabelValues(name, address)
+}
+
+func IncNotifications(notifier, result string) {
+	notificationsTotal.WithLabelValues(notifier, result).Inc()
+}
+
+func IncAPIAuthDenied(reason string) {
+	apiAuthDenied.WithLabelValues(reason).Inc()
+}
+
 func ObserveHealthcheck(name, typeStr, address string, start time.Time, result bool) {
 	// Log the health check result
 	// log.Debugf("Record health check for metrics: type=%s, address=%s, result=%t", typeStr, address, result)
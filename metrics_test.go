@@ -3,6 +3,7 @@ package gslb
 import (
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -189,6 +190,46 @@ func TestMetrics_BackendSelected(t *testing.T) {
 	}
 }
 
+func TestMetrics_RecordFallback(t *testing.T) {
+	RegisterMetrics()
+	IncRecordFallback("fallback.example.com.", "static")
+	IncRecordFallback("fallback.example.com.", "static")
+	IncRecordFallback("fallback.example.com.", "none")
+
+	val := testutil.ToFloat64(recordFallback.WithLabelValues("fallback.example.com.", "static"))
+	if val != 2 {
+		t.Errorf("expected 2, got %v", val)
+	}
+	val = testutil.ToFloat64(recordFallback.WithLabelValues("fallback.example.com.", "none"))
+	if val != 1 {
+		t.Errorf("expected 1, got %v", val)
+	}
+}
+
+func TestMetrics_BackendConsecutiveChecks(t *testing.T) {
+	RegisterMetrics()
+	SetBackendConsecutiveChecks("rise.example.com.", "1.2.3.4", 0, 2)
+
+	val := testutil.ToFloat64(backendConsecutiveChecks.WithLabelValues("rise.example.com.", "1.2.3.4", "success"))
+	if val != 0 {
+		t.Errorf("expected 0, got %v", val)
+	}
+	val = testutil.ToFloat64(backendConsecutiveChecks.WithLabelValues("rise.example.com.", "1.2.3.4", "failure"))
+	if val != 2 {
+		t.Errorf("expected 2, got %v", val)
+	}
+}
+
+func TestMetrics_BackendMaintenance(t *testing.T) {
+	RegisterMetrics()
+	SetBackendMaintenance("maint.example.com.", "1.2.3.4", 1)
+
+	val := testutil.ToFloat64(backendMaintenance.WithLabelValues("maint.example.com.", "1.2.3.4"))
+	if val != 1 {
+		t.Errorf("expected 1, got %v", val)
+	}
+}
+
 func TestMetrics_RecordResolutionDuration(t *testing.T) {
 	recordResolutionDuration.Reset()
 	RegisterMetrics()
@@ -217,3 +258,37 @@ func TestMetrics_VersionInfo(t *testing.T) {
 		t.Errorf("expected 1, got %v", val2)
 	}
 }
+
+// hasRecordMetrics reports whether the record health status series of a record exists.
+func hasRecordMetrics(t *testing.T, name string) bool {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(recordHealthStatus)
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestMetrics_ReleaseRecordMetrics(t *testing.T) {
+	RegisterMetrics()
+	name := "released.example.com."
+	SetRecordHealthStatus(name, 1)
+	SetBackendHealthStatus(name, "10.0.0.1", 1)
+
+	// Two instances monitor the record during a Corefile reload, the metrics are kept until both stop
+	trackRecordMetrics(name)
+	trackRecordMetrics(name)
+	releaseRecordMetrics(name)
+	assert.True(t, hasRecordMetrics(t, name))
+	releaseRecordMetrics(name)
+	assert.False(t, hasRecordMetrics(t, name))
+	assert.False(t, backendHealthStatus.DeleteLabelValues(name, "10.0.0.1"), "backend series already deleted")
+}
@@ -0,0 +1,355 @@
+package gslb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// notifierMaxBackoff caps the delay between two delivery attempts of a notification.
+const notifierMaxBackoff = time.Minute
+
+// Notifier sends the events of the event bus to a webhook (Slack, PagerDuty, generic HTTP endpoint).
+type Notifier struct {
+	Name         string            `yaml:"name"`
+	URL          string            `yaml:"url"`
+	Headers      map[string]string `yaml:"headers"`
+	ContentType  string            `yaml:"content_type"`  // Content-Type of the request (default application/json)
+	Template     string            `yaml:"template"`      // Go template of the request body, the notification as JSON if empty
+	Events       []string          `yaml:"events"`        // Event types sent (default record_status and backend_status)
+	Statuses     []string          `yaml:"statuses"`      // Only send events with these statuses, all if empty
+	Records      []string          `yaml:"records"`       // Only send events of these records, *.example.org. matches subdomains
+	Tags         []string          `yaml:"tags"`          // Only send events of backends (or records with a backend) with one of these tags
+	Owners       []string          `yaml:"owners"`        // Only send events of records with one of these owners
+	Timeout      string            `yaml:"timeout"`       // Timeout of a delivery attempt (default 5s)
+	Retries      int               `yaml:"retries"`       // Delivery attempts after the first failed one (default 3)
+	RetryBackoff string            `yaml:"retry_backoff"` // Delay before the first retry, doubled at each retry (default 1s)
+	Debounce     string            `yaml:"debounce"`      // Wait this long for a state to settle before sending (default 0, disabled)
+
+	template     *template.Template
+	client       *http.Client
+	retryBackoff time.Duration
+	debounce     time.Duration
+	mutex        sync.Mutex
+	pending      map[string]*pendingNotification
+}
+
+// notification is the data of a notification, available to the body template.
+type notification struct {
+	Event
+	Notifier    string   `json:"notifier"`
+	Owner       string   `json:"owner,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// pendingNotification is a state change waiting for the end of the debounce window.
+type pendingNotification struct {
+	previous string // Status before the first change of the window
+	latest   notification
+}
+
+// UnmarshalYAML applies the defaults and validates the notifier.
+func (n *Notifier) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawNotifier Notifier
+	raw := rawNotifier{
+		ContentType:  "application/json",
+		Events:       []string{EventRecordStatus, EventBackendStatus},
+		Timeout:      "5s",
+		Retries:      3,
+		RetryBackoff: "1s",
+		Debounce:     "0s",
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	n.Name = raw.Name
+	n.URL = raw.URL
+	n.Headers = raw.Headers
+	n.ContentType = raw.ContentType
+	n.Template = raw.Template
+	n.Events = raw.Events
+	n.Statuses = raw.Statuses
+	n.Records = raw.Records
+	n.Tags = raw.Tags
+	n.Owners = raw.Owners
+	n.Timeout = raw.Timeout
+	n.Retries = raw.Retries
+	n.RetryBackoff = raw.RetryBackoff
+	n.Debounce = raw.Debounce
+	return n.validate()
+}
+
+// validate checks the notifier and prepares its template, client and durations.
+func (n *Notifier) validate() error {
+	if n.URL == "" {
+		return fmt.Errorf("notifier %q: url required", n.Name)
+	}
+	if n.Name == "" {
+		n.Name = n.URL
+	}
+	for _, typ := range n.Events {
+		switch typ {
+		case EventBackendStatus, EventRecordStatus, EventConfigReload, EventBackendEnable:
+		default:
+			return fmt.Errorf("notifier %q: unknown event type %q", n.Name, typ)
+		}
+	}
+	if n.Retries < 0 {
+		return fmt.Errorf("notifier %q: invalid value for retries: %d", n.Name, n.Retries)
+	}
+	timeout, err := time.ParseDuration(n.Timeout)
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("notifier %q: invalid value for timeout, expected duration format: %v", n.Name, n.Timeout)
+	}
+	n.retryBackoff, err = time.ParseDuration(n.RetryBackoff)
+	if err != nil || n.retryBackoff < 0 {
+		return fmt.Errorf("notifier %q: invalid value for retry_backoff, expected duration format: %v", n.Name, n.RetryBackoff)
+	}
+	n.debounce, err = time.ParseDuration(n.Debounce)
+	if err != nil || n.debounce < 0 {
+		return fmt.Errorf("notifier %q: invalid value for debounce, expected duration format: %v", n.Name, n.Debounce)
+	}
+	for i, record := range n.Records {
+		n.Records[i] = strings.ToLower(dns.Fqdn(record))
+	}
+	if n.Template != "" {
+		tmpl, err := template.New(n.Name).Funcs(template.FuncMap{"json": templateJSON}).Parse(n.Template)
+		if err != nil {
+			return fmt.Errorf("notifier %q: invalid template: %w", n.Name, err)
+		}
+		n.template = tmpl
+	}
+	n.client = &http.Client{Timeout: timeout}
+	n.pending = make(map[string]*pendingNotification)
+	return nil
+}
+
+// templateJSON encodes a value as JSON, to build JSON bodies from templates.
+func templateJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// loadNotifiers reads the notifiers from a YAML file with a top-level notifiers list.
+func loadNotifiers(path string) ([]*Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Notifiers []*Notifier `yaml:"notifiers"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return cfg.Notifiers, nil
+}
+
+// matches reports whether a notification passes the filters of the notifier.
+func (n *Notifier) matches(data notification) bool {
+	if !containsString(n.Events, data.Type) {
+		return false
+	}
+	if len(n.Statuses) > 0 && !containsString(n.Statuses, data.Status) {
+		return false
+	}
+	if len(n.Records) > 0 && !matchRecord(n.Records, strings.ToLower(data.Record)) {
+		return false
+	}
+	if len(n.Owners) > 0 && !containsString(n.Owners, data.Owner) {
+		return false
+	}
+	if len(n.Tags) > 0 {
+		for _, tag := range n.Tags {
+			if containsString(data.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchRecord reports whether a record matches one of the names, *.example.org. matching the subdomains.
+func matchRecord(names []string, record string) bool {
+	if record == "" {
+		return false
+	}
+	for _, name := range names {
+		if name == record || (strings.HasPrefix(name, "*.") && strings.HasSuffix(record, name[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// notify sends a notification, at the end of the debounce window if there is one. Within a window,
+// the changes of the same record or backend are merged, and nothing is sent if it is back to its
+// previous status.
+func (n *Notifier) notify(ctx context.Context, data notification) {
+	if n.debounce == 0 {
+		go n.send(ctx, data)
+		return
+	}
+	key := data.Type + "|" + data.Record + "|" + data.Address
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if p, ok := n.pending[key]; ok {
+		p.latest = data
+		return
+	}
+	n.pending[key] = &pendingNotification{previous: data.Previous, latest: data}
+	time.AfterFunc(n.debounce, func() {
+		n.mutex.Lock()
+		p := n.pending[key]
+		delete(n.pending, key)
+		n.mutex.Unlock()
+		if p.previous != "" && p.latest.Status == p.previous {
+			log.Debugf("notifier %s: %s of %s %s back to %s, not sent", n.Name, data.Type, data.Record, data.Address, p.previous)
+			return
+		}
+		latest := p.latest
+		latest.Previous = p.previous
+		n.send(ctx, latest)
+	})
+}
+
+// send delivers a notification, retrying with an exponential backoff.
+func (n *Notifier) send(ctx context.Context, data notification) {
+	body, err := n.render(data)
+	if err != nil {
+		log.Errorf("notifier %s: failed to render the notification: %v", n.Name, err)
+		IncNotifications(n.Name, "failure")
+		return
+	}
+	backoff := n.retryBackoff
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return
+		}
+		err := n.post(ctx, body)
+		if err == nil {
+			IncNotifications(n.Name, "success")
+			return
+		}
+		if attempt >= n.Retries {
+			log.Errorf("notifier %s: failed to send %s event of %s after %d attempts: %v", n.Name, data.Type, data.Record, attempt+1, err)
+			IncNotifications(n.Name, "failure")
+			return
+		}
+		log.Warningf("notifier %s: attempt %d failed, retrying in %s: %v", n.Name, attempt+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > notifierMaxBackoff {
+			backoff = notifierMaxBackoff
+		}
+	}
+}
+
+// render builds the request body of a notification.
+func (n *Notifier) render(data notification) ([]byte, error) {
+	if n.template == nil {
+		return json.Marshal(data)
+	}
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends a request body to the webhook, any status other than 2xx is an error.
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", n.ContentType)
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// startNotifiers subscribes to the event bus and sends the events to the notifiers until ctx is done.
+func (g *GSLB) startNotifiers(ctx context.Context, notifiers []*Notifier) {
+	ch := events.Subscribe()
+	go func() {
+		defer events.Unsubscribe(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-ch:
+				for _, n := range notifiers {
+					data := g.notificationData(event, n.Name)
+					if n.matches(data) {
+						n.notify(ctx, data)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// notificationData adds the owner, description and tags of the record to an event.
+// The tags are those of the backend for backend events, of all the backends otherwise.
+func (g *GSLB) notificationData(event Event, notifier string) notification {
+	data := notification{Event: event, Notifier: notifier}
+	if event.Record == "" {
+		return data
+	}
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	record, _ := g.findRecord(event.Record)
+	if record == nil {
+		return data
+	}
+	data.Owner = record.Owner
+	data.Description = record.Description
+	for _, backend := range record.Backends {
+		if event.Address != "" && backend.GetAddress() != event.Address {
+			continue
+		}
+		for _, tag := range backend.GetTags() {
+			if !containsString(data.Tags, tag) {
+				data.Tags = append(data.Tags, tag)
+			}
+		}
+	}
+	return data
+}
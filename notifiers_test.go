@@ -0,0 +1,187 @@
+package gslb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// webhookServer is a local stand-in for a webhook, failing the first requests.
+type webhookServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	failures int
+	attempts int
+	bodies   []string
+}
+
+func newWebhookServer(t *testing.T, failures int) *webhookServer {
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.attempts++
+		if s.attempts <= s.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.bodies = append(s.bodies, string(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestNotifier(t *testing.T, config string) *Notifier {
+	var n Notifier
+	assert.NoError(t, yaml.Unmarshal([]byte(config), &n))
+	return &n
+}
+
+func TestLoadNotifiers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notifiers.yml")
+	assert.NoError(t, os.WriteFile(file, []byte(`
+notifiers:
+  - name: slack
+    url: https://hooks.slack.com/services/T000/B000/XXXX
+    template: '{"text": {{printf "%s is %s" .Record .Status | json}}}'
+    records: [app.example.com]
+    debounce: 30s
+  - url: http://127.0.0.1:9000/hook
+    events: [config_reload]
+    retries: 0
+`), 0644))
+	notifiers, err := loadNotifiers(file)
+	assert.NoError(t, err)
+	assert.Len(t, notifiers, 2)
+
+	slack := notifiers[0]
+	assert.Equal(t, []string{EventRecordStatus, EventBackendStatus}, slack.Events)
+	assert.Equal(t, []string{"app.example.com."}, slack.Records)
+	assert.Equal(t, 3, slack.Retries)
+	assert.Equal(t, time.Second, slack.retryBackoff)
+	assert.Equal(t, 30*time.Second, slack.debounce)
+	assert.Equal(t, "application/json", slack.ContentType)
+
+	// The URL names unnamed notifiers
+	assert.Equal(t, "http://127.0.0.1:9000/hook", notifiers[1].Name)
+	assert.Equal(t, 0, notifiers[1].Retries)
+
+	invalid := []string{
+		`name: nourl`,
+		`{url: "http://localhost", events: [unknown]}`,
+		`{url: "http://localhost", timeout: soon}`,
+		`{url: "http://localhost", debounce: -1s}`,
+		`{url: "http://localhost", retries: -1}`,
+		`{url: "http://localhost", template: "{{.Record"}`,
+	}
+	for _, config := range invalid {
+		var n Notifier
+		assert.Error(t, yaml.Unmarshal([]byte(config), &n), config)
+	}
+}
+
+func TestNotifier_Matches(t *testing.T) {
+	n := newTestNotifier(t, `{url: "http://localhost", statuses: [unhealthy], records: ["*.example.com"], owners: [team-a], tags: [prod]}`)
+	data := notification{
+		Event: Event{Type: EventRecordStatus, Record: "app.example.com.", Status: "unhealthy"},
+		Owner: "team-a",
+		Tags:  []string{"eu", "prod"},
+	}
+	assert.True(t, n.matches(data))
+
+	other := data
+	other.Type = EventConfigReload
+	assert.False(t, n.matches(other))
+	other = data
+	other.Status = "healthy"
+	assert.False(t, n.matches(other))
+	other = data
+	other.Record = "app.example.org."
+	assert.False(t, n.matches(other))
+	other = data
+	other.Owner = "team-b"
+	assert.False(t, n.matches(other))
+	other = data
+	other.Tags = []string{"eu"}
+	assert.False(t, n.matches(other))
+
+	// Without filters every event of the configured types matches
+	n = newTestNotifier(t, `{url: "http://localhost"}`)
+	assert.True(t, n.matches(notification{Event: Event{Type: EventBackendStatus}}))
+}
+
+func TestNotifier_SendRetries(t *testing.T) {
+	server := newWebhookServer(t, 2)
+	n := newTestNotifier(t, `{url: "`+server.URL+`", retries: 2, retry_backoff: 1ms, template: '{"text": {{printf "%s is %s" .Record .Status | json}}}'}`)
+	n.send(context.Background(), notification{Event: Event{Type: EventRecordStatus, Record: "app.example.com.", Status: "unhealthy"}})
+	assert.Equal(t, 3, server.attempts)
+	assert.Equal(t, []string{`{"text": "app.example.com. is unhealthy"}`}, server.received())
+
+	// Given up after the retries
+	server = newWebhookServer(t, 10)
+	n = newTestNotifier(t, `{url: "`+server.URL+`", retries: 1, retry_backoff: 1ms}`)
+	n.send(context.Background(), notification{Event: Event{Type: EventRecordStatus}})
+	assert.Equal(t, 2, server.attempts)
+	assert.Empty(t, server.received())
+}
+
+func TestNotifier_Debounce(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	n := newTestNotifier(t, `{url: "`+server.URL+`", debounce: 50ms}`)
+	down := notification{Event: Event{Type: EventBackendStatus, Record: "app.example.com.", Address: "10.0.0.1", Status: "unhealthy", Previous: "healthy"}}
+	up := notification{Event: Event{Type: EventBackendStatus, Record: "app.example.com.", Address: "10.0.0.1", Status: "healthy", Previous: "unhealthy"}}
+
+	// A flap within the window is not sent
+	n.notify(context.Background(), down)
+	n.notify(context.Background(), up)
+	time.Sleep(150 * time.Millisecond)
+	assert.Empty(t, server.received())
+
+	// Several changes are merged into one notification from the first previous status
+	n.notify(context.Background(), down)
+	n.notify(context.Background(), up)
+	n.notify(context.Background(), down)
+	assert.Eventually(t, func() bool { return len(server.received()) == 1 }, time.Second, 10*time.Millisecond)
+	var sent notification
+	assert.NoError(t, json.Unmarshal([]byte(server.received()[0]), &sent))
+	assert.Equal(t, "unhealthy", sent.Status)
+	assert.Equal(t, "healthy", sent.Previous)
+}
+
+func TestGSLB_Notifiers(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	n := newTestNotifier(t, `{name: ops, url: "`+server.URL+`", records: [notify.example.com.], owners: [team-a], tags: [prod]}`)
+	backend := &Backend{Fqdn: "notify.example.com.", Address: "10.0.0.1", Enable: true, Tags: []string{"prod"}}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"notify.example.com.": {Fqdn: "notify.example.com.", Owner: "team-a", Backends: []BackendInterface{backend}}},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.startNotifiers(ctx, []*Notifier{n})
+
+	events.Publish(Event{Type: EventRecordStatus, Record: "notify.example.com.", Status: "unhealthy", Previous: "healthy"})
+	assert.Eventually(t, func() bool { return len(server.received()) == 1 }, time.Second, 10*time.Millisecond)
+	var sent notification
+	assert.NoError(t, json.Unmarshal([]byte(server.received()[0]), &sent))
+	assert.Equal(t, "ops", sent.Notifier)
+	assert.Equal(t, "team-a", sent.Owner)
+	assert.Equal(t, []string{"prod"}, sent.Tags)
+	assert.Equal(t, EventRecordStatus, sent.Type)
+}
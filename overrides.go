@@ -0,0 +1,344 @@
+package gslb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// overrideSweepInterval is how often expired overrides are cleared.
+const overrideSweepInterval = 5 * time.Second
+
+// BackendOverride is a runtime enable/disable of a backend set through the API.
+// Overrides take precedence over the zone file, which is never rewritten.
+type BackendOverride struct {
+	Record    string     `json:"record"`
+	Address   string     `json:"address"`
+	Enable    bool       `json:"enable"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Never expires if nil
+}
+
+// BackendMaintenance is a maintenance window created through the API for a backend.
+type BackendMaintenance struct {
+	Record  string `json:"record"`
+	Address string `json:"address"`
+	MaintenanceWindow
+}
+
+// overridesFile is the content of the overrides file.
+type overridesFile struct {
+	Overrides   []BackendOverride    `json:"overrides"`
+	Maintenance []BackendMaintenance `json:"maintenance"`
+}
+
+func (o *BackendOverride) expired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+func overrideKey(record, address string) string {
+	return record + "|" + address
+}
+
+// backendMatches reports whether a backend matches the location, address prefix or one of the tags.
+func backendMatches(backend BackendInterface, location, addressPrefix string, tags []string) bool {
+	if location != "" && backend.GetLocation() == location {
+		return true
+	}
+	if addressPrefix != "" && strings.HasPrefix(backend.GetAddress(), addressPrefix) {
+		return true
+	}
+	for _, t := range tags {
+		for _, btag := range backend.GetTags() {
+			if t == btag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setOverride enables or disables a backend at runtime. The override is only kept while it
+// differs from the zone file, so enabling a drained backend simply clears its override.
+// The caller must hold g.Mutex.
+func (g *GSLB) setOverride(fqdn string, backend BackendInterface, enable bool, reason string, expiresAt *time.Time) {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	if g.Overrides == nil {
+		g.Overrides = make(map[string]*BackendOverride)
+	}
+	key := overrideKey(fqdn, backend.GetAddress())
+	previous := backend.IsEnabled()
+	if backend.getConfigEnable() == enable {
+		delete(g.Overrides, key)
+		backend.clearEnableOverride()
+	} else {
+		g.Overrides[key] = &BackendOverride{
+			Record:    fqdn,
+			Address:   backend.GetAddress(),
+			Enable:    enable,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+			ExpiresAt: expiresAt,
+		}
+		backend.setEnableOverride(enable)
+	}
+	publishEnableChange(fqdn, backend, previous, reason)
+}
+
+// publishEnableChange publishes the enable/disable of a backend through the API, if its value changed.
+func publishEnableChange(fqdn string, backend BackendInterface, previous bool, reason string) {
+	if backend.IsEnabled() == previous {
+		return
+	}
+	events.Publish(Event{
+		Type:     EventBackendEnable,
+		Record:   fqdn,
+		Address:  backend.GetAddress(),
+		Status:   enableStatus(backend.IsEnabled()),
+		Previous: enableStatus(previous),
+		Reason:   reason,
+	})
+}
+
+// clearOverride removes the override of a backend and restores its zone file value.
+// It returns false if the backend has no override. The caller must hold g.Mutex.
+func (g *GSLB) clearOverride(fqdn, address string) bool {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	key := overrideKey(fqdn, address)
+	if _, ok := g.Overrides[key]; !ok {
+		return false
+	}
+	delete(g.Overrides, key)
+	if record, _ := g.findRecord(fqdn); record != nil {
+		for _, backend := range record.Backends {
+			if backend.GetAddress() == address {
+				previous := backend.IsEnabled()
+				backend.clearEnableOverride()
+				publishEnableChange(fqdn, backend, previous, "override cleared")
+			}
+		}
+	}
+	return true
+}
+
+// listOverrides returns the active overrides sorted by record and address.
+func (g *GSLB) listOverrides() []BackendOverride {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	overrides := make([]BackendOverride, 0, len(g.Overrides))
+	for _, o := range g.Overrides {
+		overrides = append(overrides, *o)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Record != overrides[j].Record {
+			return overrides[i].Record < overrides[j].Record
+		}
+		return overrides[i].Address < overrides[j].Address
+	})
+	return overrides
+}
+
+// addMaintenance schedules a maintenance window created through the API on a backend.
+// The caller must hold g.Mutex.
+func (g *GSLB) addMaintenance(fqdn string, backend BackendInterface, window MaintenanceWindow) {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	if g.Maintenance == nil {
+		g.Maintenance = make(map[string][]MaintenanceWindow)
+	}
+	key := overrideKey(fqdn, backend.GetAddress())
+	g.Maintenance[key] = append(g.Maintenance[key], window)
+	backend.setRuntimeMaintenance(append([]MaintenanceWindow(nil), g.Maintenance[key]...))
+}
+
+// removeMaintenance deletes the maintenance windows created through the API with the given ID.
+// It returns false if no window has this ID. The caller must hold g.Mutex.
+func (g *GSLB) removeMaintenance(id string) bool {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	var affected []string
+	for key, windows := range g.Maintenance {
+		kept := windows[:0:0]
+		for _, w := range windows {
+			if w.ID != id {
+				kept = append(kept, w)
+			}
+		}
+		if len(kept) == len(windows) {
+			continue
+		}
+		affected = append(affected, key)
+		if len(kept) == 0 {
+			delete(g.Maintenance, key)
+		} else {
+			g.Maintenance[key] = kept
+		}
+	}
+	// Only the backends that had the window change
+	for _, key := range affected {
+		record, address, _ := strings.Cut(key, "|")
+		if backend := g.findBackend(record, address); backend != nil {
+			backend.setRuntimeMaintenance(append([]MaintenanceWindow(nil), g.Maintenance[key]...))
+		}
+	}
+	return len(affected) > 0
+}
+
+// listMaintenance returns the maintenance windows created through the API, sorted by record, address and start.
+func (g *GSLB) listMaintenance() []BackendMaintenance {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	list := []BackendMaintenance{}
+	for key, windows := range g.Maintenance {
+		record, address, _ := strings.Cut(key, "|")
+		for _, w := range windows {
+			list = append(list, BackendMaintenance{Record: record, Address: address, MaintenanceWindow: w})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Record != list[j].Record {
+			return list[i].Record < list[j].Record
+		}
+		if list[i].Address != list[j].Address {
+			return list[i].Address < list[j].Address
+		}
+		return list[i].Start.Before(list[j].Start)
+	})
+	return list
+}
+
+// applyOverrides applies the active overrides and maintenance windows to the configured backends,
+// after a load or a reload. The caller must hold g.Mutex.
+func (g *GSLB) applyOverrides() {
+	g.overridesMutex.Lock()
+	defer g.overridesMutex.Unlock()
+	for _, records := range g.Records {
+		for fqdn, record := range records {
+			for _, backend := range record.Backends {
+				key := overrideKey(fqdn, backend.GetAddress())
+				if o, ok := g.Overrides[key]; ok {
+					backend.setEnableOverride(o.Enable)
+				}
+				backend.setRuntimeMaintenance(append([]MaintenanceWindow(nil), g.Maintenance[key]...))
+			}
+		}
+	}
+}
+
+// expireOverrides clears the overrides that reached their expiry time, and the maintenance
+// windows created through the API that are over.
+func (g *GSLB) expireOverrides() {
+	now := time.Now()
+	var expired []BackendOverride
+	for _, o := range g.listOverrides() {
+		if o.expired(now) {
+			expired = append(expired, o)
+		}
+	}
+	var ended []string
+	for _, m := range g.listMaintenance() {
+		if m.expired(now) {
+			ended = append(ended, m.ID)
+		}
+	}
+	if len(expired) == 0 && len(ended) == 0 {
+		return
+	}
+
+	g.Mutex.Lock()
+	for _, o := range expired {
+		log.Infof("[%s] override of backend %s expired", o.Record, o.Address)
+		g.clearOverride(o.Record, o.Address)
+	}
+	for _, id := range ended {
+		g.removeMaintenance(id)
+	}
+	g.Mutex.Unlock()
+	if err := g.saveOverrides(); err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+// sweepOverrides periodically clears expired overrides until the context is cancelled.
+func (g *GSLB) sweepOverrides(ctx context.Context) {
+	ticker := time.NewTicker(overrideSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.expireOverrides()
+		}
+	}
+}
+
+// saveOverrides writes the active overrides to the overrides file, if configured.
+func (g *GSLB) saveOverrides() error {
+	if g.OverridesFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(overridesFile{Overrides: g.listOverrides(), Maintenance: g.listMaintenance()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode overrides: %w", err)
+	}
+	if err := writeFileAtomic(g.OverridesFile, data); err != nil {
+		return fmt.Errorf("failed to write overrides file: %w", err)
+	}
+	return nil
+}
+
+// loadOverrides reads the overrides file and applies the overrides and maintenance windows that are not over yet.
+// The caller must hold g.Mutex.
+func (g *GSLB) loadOverrides() error {
+	if g.OverridesFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(g.OverridesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read overrides file: %w", err)
+	}
+	var content overridesFile
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("failed to parse overrides file: %w", err)
+	}
+
+	g.overridesMutex.Lock()
+	g.Overrides = make(map[string]*BackendOverride)
+	g.Maintenance = make(map[string][]MaintenanceWindow)
+	now := time.Now()
+	for i, o := range content.Overrides {
+		if o.expired(now) {
+			continue
+		}
+		g.Overrides[overrideKey(o.Record, o.Address)] = &content.Overrides[i]
+	}
+	windows := 0
+	for _, m := range content.Maintenance {
+		if err := m.validate(); err != nil {
+			log.Errorf("[%s] ignoring maintenance window %s of backend %s: %v", m.Record, m.ID, m.Address, err)
+			continue
+		}
+		if m.expired(now) {
+			continue
+		}
+		key := overrideKey(m.Record, m.Address)
+		g.Maintenance[key] = append(g.Maintenance[key], m.MaintenanceWindow)
+		windows++
+	}
+	log.Infof("Loaded %d backend overrides and %d maintenance windows from %s", len(g.Overrides), windows, g.OverridesFile)
+	g.overridesMutex.Unlock()
+
+	g.applyOverrides()
+	return nil
+}
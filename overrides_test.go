@@ -0,0 +1,82 @@
+package gslb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGSLB_Override_KeptOnReload(t *testing.T) {
+	backend := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}}},
+	}}
+	g.setOverride("app.example.com.", backend, false, "", nil)
+	assert.False(t, backend.IsEnabled())
+
+	// A reload of the zone file does not undo the override
+	newGSLB := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{&Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true}}}},
+	}}
+	g.updateRecords(context.Background(), newGSLB)
+	assert.False(t, backend.IsEnabled())
+
+	// Enabling a backend enabled in the zone file clears the override
+	g.setOverride("app.example.com.", backend, true, "", nil)
+	assert.True(t, backend.IsEnabled())
+	assert.Empty(t, g.listOverrides())
+}
+
+func TestGSLB_Override_ClearRestoresZoneFile(t *testing.T) {
+	backend := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: false}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}}},
+	}}
+	g.setOverride("app.example.com.", backend, true, "", nil)
+	assert.True(t, backend.IsEnabled())
+
+	assert.True(t, g.clearOverride("app.example.com.", "10.0.0.1"))
+	assert.False(t, backend.IsEnabled())
+	assert.False(t, g.clearOverride("app.example.com.", "10.0.0.1"))
+}
+
+func TestGSLB_Override_Expire(t *testing.T) {
+	backend := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true}
+	g := &GSLB{Records: map[string]map[string]*Record{
+		"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}}},
+	}}
+	past := time.Now().Add(-time.Second)
+	g.setOverride("app.example.com.", backend, false, "", &past)
+	assert.False(t, backend.IsEnabled())
+
+	g.expireOverrides()
+	assert.True(t, backend.IsEnabled())
+	assert.Empty(t, g.listOverrides())
+}
+
+func TestGSLB_Override_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	backend := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true}
+	g := &GSLB{
+		OverridesFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{backend}}},
+		},
+	}
+	g.setOverride("app.example.com.", backend, false, "drain", nil)
+	assert.NoError(t, g.saveOverrides())
+
+	restored := &Backend{Fqdn: "app.example.com.", Address: "10.0.0.1", Enable: true}
+	g2 := &GSLB{
+		OverridesFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{restored}}},
+		},
+	}
+	assert.NoError(t, g2.loadOverrides())
+	assert.False(t, restored.IsEnabled())
+	assert.Equal(t, "drain", g2.listOverrides()[0].Reason)
+}
@@ -0,0 +1,88 @@
+package gslb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// setZoneError records the error of the last load of a zone file, nil clears it.
+// The caller holds g.Mutex, or the API is not started yet.
+func (g *GSLB) setZoneError(zone string, err error) {
+	if err == nil {
+		delete(g.zoneErrors, zone)
+		return
+	}
+	if g.zoneErrors == nil {
+		g.zoneErrors = make(map[string]string)
+	}
+	g.zoneErrors[zone] = err.Error()
+}
+
+// notReadyReasons returns why the plugin is not ready to answer with real health data, or nil.
+// The zone files must be loaded, and every record must have completed its first health pass:
+// until then the records are answered with all their addresses.
+func (g *GSLB) notReadyReasons() []string {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	var reasons []string
+	for zone := range g.Zones {
+		if err, ok := g.zoneErrors[zone]; ok {
+			reasons = append(reasons, fmt.Sprintf("zone %s failed to load: %s", zone, err))
+		} else if _, ok := g.Records[zone]; !ok {
+			reasons = append(reasons, fmt.Sprintf("zone %s is not loaded", zone))
+		}
+	}
+	sort.Strings(reasons)
+
+	// Once reached with all the zones loaded, records added later do not make the plugin unready
+	if !g.ready.Load() {
+		pending := 0
+		for _, records := range g.Records {
+			for _, record := range records {
+				if !record.healthChecked() {
+					pending++
+				}
+			}
+		}
+		if pending > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d record(s) waiting for their first health check", pending))
+		} else if len(reasons) == 0 {
+			g.ready.Store(true)
+		}
+	}
+	return reasons
+}
+
+// handleHealthz returns a handler for the liveness probe (GET /healthz). It does not require authentication.
+func (g *GSLB) handleHealthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET is supported."})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+// handleReadyz returns a handler for the readiness probe (GET /readyz), answering 503 with the
+// reasons while the plugin is not ready. It does not require authentication.
+func (g *GSLB) handleReadyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed. Only GET is supported."})
+			return
+		}
+		if reasons := g.notReadyReasons(); len(reasons) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "not ready", "reasons": reasons})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	}
+}
@@ -0,0 +1,100 @@
+package gslb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const readinessZoneFile = `
+defaults:
+  scrape_interval: 50ms
+records:
+  app.example.com.:
+    backends:
+      - address: 1.2.3.4
+        enable: false
+  web.example.com.:
+    backends:
+      - address: 1.2.3.5
+        enable: false
+`
+
+func getProbe(t *testing.T, url string) (int, map[string]interface{}) {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestReadiness(t *testing.T) {
+	zoneFile := filepath.Join(t.TempDir(), "db.example.com.yml")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(readinessZoneFile), 0644))
+	g := &GSLB{Zones: map[string]string{"example.com.": zoneFile}, MaxStaggerStart: "100ms", BatchSizeStart: 1}
+	t.Cleanup(configReloads.Reset)
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Not ready before the records are loaded and checked
+	code, body := getProbe(t, ts.URL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, []interface{}{"zone example.com. is not loaded"}, body["reasons"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.initializeRecordsFromFiles(ctx, g.Zones)
+	code, body = getProbe(t, ts.URL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, []interface{}{"2 record(s) waiting for their first health check"}, body["reasons"])
+
+	assert.Eventually(t, func() bool {
+		code, _ := getProbe(t, ts.URL+"/readyz")
+		return code == http.StatusOK
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// A zone file failing to load makes it unready until fixed
+	assert.NoError(t, os.WriteFile(zoneFile, []byte("records: ["), 0644))
+	assert.Error(t, reloadConfig(g, zoneFile, "example.com."))
+	code, body = getProbe(t, ts.URL+"/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body["reasons"].([]interface{})[0], "zone example.com. failed to load")
+	assert.NoError(t, os.WriteFile(zoneFile, []byte(readinessZoneFile), 0644))
+	assert.NoError(t, reloadConfig(g, zoneFile, "example.com."))
+	code, body = getProbe(t, ts.URL+"/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body["status"])
+}
+
+func TestReadiness_ZoneLoadFailure(t *testing.T) {
+	g := &GSLB{Zones: map[string]string{"example.com.": filepath.Join(t.TempDir(), "missing.yml")}}
+	g.initializeRecordsFromFiles(context.Background(), g.Zones)
+	reasons := g.notReadyReasons()
+	assert.Len(t, reasons, 1)
+	assert.Contains(t, reasons[0], "zone example.com. failed to load")
+}
+
+func TestHealthz(t *testing.T) {
+	g := &GSLB{APIBasicUser: "admin", APIBasicPass: "secret"}
+	mux := http.NewServeMux()
+	g.RegisterAPIHandlers(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Probes do not require authentication
+	code, body := getProbe(t, ts.URL+"/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body["status"])
+	code, _ = getProbe(t, ts.URL+"/readyz")
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -2,11 +2,14 @@ package gslb
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/miekg/dns"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,6 +17,7 @@ import (
 type Record struct {
 	Fqdn           string
 	Mode           string
+	Policy         []string // Stages of the routing policy, replacing the mode when set
 	Backends       []BackendInterface
 	Owner          string
 	Description    string
@@ -21,21 +25,89 @@ type Record struct {
 	ScrapeInterval string
 	ScrapeRetries  int
 	ScrapeTimeout  string
-	ticker         *time.Ticker
-	mutex          sync.RWMutex
-	cancelFunc     context.CancelFunc
+	HTTPS          HTTPSConfig
+	// Fallback is the policy applied when no backend is healthy (all, none, static, last_healthy, cname)
+	Fallback          string
+	FallbackAddresses []string // Addresses answered by the static policy
+	FallbackCNAME     string   // Hostname answered by the cname policy
+	DegradedTTL       int      // TTL answered while the record is degraded (0 disables it)
+	DegradedHoldDown  string   // How long the degraded TTL is kept after a backend status change
+	MaxAnswers        int      // Maximum number of addresses answered, 0 for the default of the mode
+	lastHealthy       []BackendInterface
+	degraded          bool
+	healthy           bool // Health status of the last update, valid if healthKnown
+	healthKnown       bool
+	checked           bool // Set once the health checks of all backends ran, for the readiness
+	ticker            *time.Ticker
+	mutex             sync.RWMutex
+	cancelFunc        context.CancelFunc
+}
+
+// Fallback policies applied when no backend is healthy.
+const (
+	FallbackAll         = "all"
+	FallbackNone        = "none"
+	FallbackStatic      = "static"
+	FallbackLastHealthy = "last_healthy"
+	FallbackCNAME       = "cname"
+)
+
+// HTTPSConfig holds the service parameters announced in HTTPS/SVCB answers for a record.
+type HTTPSConfig struct {
+	Priority int      `yaml:"priority"` // SvcPriority of the ServiceMode answer (default 1)
+	ALPN     []string `yaml:"alpn"`     // Supported protocols, e.g. h2, h3
+	Port     int      `yaml:"port"`     // Alternative port, omitted when 0
+	ECH      string   `yaml:"ech"`      // Base64 encoded ECHConfigList
+}
+
+// GetPriority returns the SvcPriority, defaulting to 1 since 0 would turn the answer into AliasMode.
+func (h HTTPSConfig) GetPriority() uint16 {
+	if h.Priority <= 0 {
+		return 1
+	}
+	return uint16(h.Priority)
+}
+
+// Equals compares two HTTPS configurations.
+func (h HTTPSConfig) Equals(other HTTPSConfig) bool {
+	return h.Priority == other.Priority && h.Port == other.Port && h.ECH == other.ECH && tagsEqual(h.ALPN, other.ALPN)
+}
+
+func (h HTTPSConfig) validate() error {
+	if h.Priority < 0 || h.Priority > 65535 {
+		return fmt.Errorf("https priority must be between 0 and 65535")
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		return fmt.Errorf("https port must be between 0 and 65535")
+	}
+	if h.ECH != "" {
+		if _, err := base64.StdEncoding.DecodeString(h.ECH); err != nil {
+			return fmt.Errorf("https ech must be base64 encoded: %w", err)
+		}
+	}
+	return nil
 }
 
 func (r *Record) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var raw struct {
-		Mode           string        `yaml:"mode" default:"failover"`
-		Owner          string        `yaml:"owner" default:""`
-		Description    string        `yaml:"description" default:""`
-		Ttl            int           `yaml:"record_ttl" default:"30"`
-		ScrapeInterval string        `yaml:"scrape_interval" default:"10s"`
-		ScrapeRetries  int           `yaml:"scrape_retries" default:"1"`
-		ScrapeTimeout  string        `yaml:"scrape_timeout" default:"5s"`
-		Backends       []interface{} `yaml:"backends"`
+		Mode              string        `yaml:"mode" default:"failover"`
+		Policy            []string      `yaml:"policy"`
+		Owner             string        `yaml:"owner" default:""`
+		Description       string        `yaml:"description" default:""`
+		Ttl               int           `yaml:"record_ttl" default:"30"`
+		ScrapeInterval    string        `yaml:"scrape_interval" default:"10s"`
+		ScrapeRetries     int           `yaml:"scrape_retries" default:"1"`
+		ScrapeTimeout     string        `yaml:"scrape_timeout" default:"5s"`
+		HTTPS             HTTPSConfig   `yaml:"https"`
+		Fallback          string        `yaml:"fallback" default:"all"`
+		FallbackAddresses []string      `yaml:"fallback_addresses"`
+		FallbackCNAME     string        `yaml:"fallback_cname"`
+		DegradedTTL       int           `yaml:"degraded_ttl" default:"0"`
+		DegradedHoldDown  string        `yaml:"degraded_hold_down" default:"60s"`
+		MaxAnswers        int           `yaml:"max_answers" default:"0"`
+		Rise              int           `yaml:"rise" default:"1"`
+		Fall              int           `yaml:"fall" default:"1"`
+		Backends          []interface{} `yaml:"backends"`
 	}
 	defaults.Set(&raw)
 
@@ -44,12 +116,41 @@ func (r *Record) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	r.Mode = raw.Mode
+	if err := validatePolicy(raw.Policy); err != nil {
+		return err
+	}
+	r.Policy = raw.Policy
 	r.Owner = raw.Owner
 	r.Description = raw.Description
 	r.RecordTTL = raw.Ttl
 	r.ScrapeInterval = raw.ScrapeInterval
 	r.ScrapeRetries = raw.ScrapeRetries
 	r.ScrapeTimeout = raw.ScrapeTimeout
+	if err := raw.HTTPS.validate(); err != nil {
+		return err
+	}
+	r.HTTPS = raw.HTTPS
+	r.Fallback = raw.Fallback
+	r.FallbackAddresses = raw.FallbackAddresses
+	r.FallbackCNAME = raw.FallbackCNAME
+	if err := r.validateFallback(); err != nil {
+		return err
+	}
+	if raw.DegradedTTL < 0 {
+		return fmt.Errorf("degraded_ttl must be positive, got %d", raw.DegradedTTL)
+	}
+	if _, err := time.ParseDuration(raw.DegradedHoldDown); err != nil {
+		return fmt.Errorf("invalid degraded_hold_down %s: %w", raw.DegradedHoldDown, err)
+	}
+	r.DegradedTTL = raw.DegradedTTL
+	r.DegradedHoldDown = raw.DegradedHoldDown
+	if raw.MaxAnswers < 0 {
+		return fmt.Errorf("max_answers must be positive, got %d", raw.MaxAnswers)
+	}
+	r.MaxAnswers = raw.MaxAnswers
+	if raw.Rise < 1 || raw.Fall < 1 {
+		return fmt.Errorf("rise and fall must be at least 1")
+	}
 
 	for _, backendData := range raw.Backends {
 		var backend Backend
@@ -62,6 +163,13 @@ func (r *Record) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		if err != nil {
 			return fmt.Errorf("failed to decode backend: %w", err)
 		}
+		// Backends without their own thresholds inherit the record ones
+		if backend.Rise == 0 {
+			backend.Rise = raw.Rise
+		}
+		if backend.Fall == 0 {
+			backend.Fall = raw.Fall
+		}
 
 		r.Backends = append(r.Backends, &backend)
 	}
@@ -79,6 +187,11 @@ func (r *Record) updateRecord(newRecord *Record) {
 		r.Mode = newRecord.Mode
 	}
 
+	if !tagsEqual(r.Policy, newRecord.Policy) {
+		log.Debugf("[%s] policy changed from %v to %v", r.Fqdn, r.Policy, newRecord.Policy)
+		r.Policy = newRecord.Policy
+	}
+
 	if r.Owner != newRecord.Owner {
 		log.Debugf("[%s] owner changed from %s to %s", r.Fqdn, r.Owner, newRecord.Owner)
 		r.Owner = newRecord.Owner
@@ -110,6 +223,33 @@ func (r *Record) updateRecord(newRecord *Record) {
 		r.ScrapeTimeout = newRecord.ScrapeTimeout
 	}
 
+	if !r.HTTPS.Equals(newRecord.HTTPS) {
+		log.Debugf("[%s] https parameters changed", r.Fqdn)
+		r.HTTPS = newRecord.HTTPS
+	}
+
+	if r.Fallback != newRecord.Fallback || r.FallbackCNAME != newRecord.FallbackCNAME || !tagsEqual(r.FallbackAddresses, newRecord.FallbackAddresses) {
+		log.Debugf("[%s] fallback changed from %s to %s", r.Fqdn, r.GetFallback(), newRecord.GetFallback())
+		r.Fallback = newRecord.Fallback
+		r.FallbackAddresses = newRecord.FallbackAddresses
+		r.FallbackCNAME = newRecord.FallbackCNAME
+	}
+
+	if r.DegradedTTL != newRecord.DegradedTTL {
+		log.Debugf("[%s] degraded TTL changed from %d to %d", r.Fqdn, r.DegradedTTL, newRecord.DegradedTTL)
+		r.DegradedTTL = newRecord.DegradedTTL
+	}
+
+	if r.DegradedHoldDown != newRecord.DegradedHoldDown {
+		log.Debugf("[%s] degraded hold-down changed from %s to %s", r.Fqdn, r.DegradedHoldDown, newRecord.DegradedHoldDown)
+		r.DegradedHoldDown = newRecord.DegradedHoldDown
+	}
+
+	if r.MaxAnswers != newRecord.MaxAnswers {
+		log.Debugf("[%s] max answers changed from %d to %d", r.Fqdn, r.MaxAnswers, newRecord.MaxAnswers)
+		r.MaxAnswers = newRecord.MaxAnswers
+	}
+
 	// Update or add backends
 	for _, newBackend := range newRecord.Backends {
 		newBackend.SetFqdn(r.Fqdn)
@@ -153,6 +293,93 @@ func (r *Record) updateRecord(newRecord *Record) {
 	}
 }
 
+// GetFallback returns the normalized fallback policy of the record.
+func (r *Record) GetFallback() string {
+	switch r.Fallback {
+	case "":
+		return FallbackAll
+	case "servfail":
+		return FallbackNone
+	default:
+		return r.Fallback
+	}
+}
+
+func (r *Record) validateFallback() error {
+	switch r.GetFallback() {
+	case FallbackAll, FallbackNone, FallbackLastHealthy:
+	case FallbackStatic:
+		if len(r.FallbackAddresses) == 0 {
+			return fmt.Errorf("fallback static requires fallback_addresses")
+		}
+		for _, addr := range r.FallbackAddresses {
+			if net.ParseIP(addr) == nil {
+				return fmt.Errorf("fallback address %s is not an IP address", addr)
+			}
+		}
+	case FallbackCNAME:
+		if _, ok := dns.IsDomainName(r.FallbackCNAME); !ok || r.FallbackCNAME == "" || net.ParseIP(r.FallbackCNAME) != nil {
+			return fmt.Errorf("fallback cname requires a valid fallback_cname hostname")
+		}
+	default:
+		return fmt.Errorf("unsupported fallback policy: %s", r.Fallback)
+	}
+	return nil
+}
+
+// getBackends returns a copy of the backends, safe to iterate while a reload updates the record.
+func (r *Record) getBackends() []BackendInterface {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]BackendInterface(nil), r.Backends...)
+}
+
+// hasBackendOfType returns true if the record has a backend able to answer the given type, healthy or not.
+func (r *Record) hasBackendOfType(recordType uint16) bool {
+	for _, backend := range r.getBackends() {
+		if backendMatchesType(backend, recordType) {
+			return true
+		}
+	}
+	return false
+}
+
+// getLastHealthy returns the backends that were healthy the last time the record had any healthy backend.
+func (r *Record) getLastHealthy() []BackendInterface {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.lastHealthy
+}
+
+// GetDegradedHoldDown returns how long the degraded TTL is kept after a backend status change.
+func (r *Record) GetDegradedHoldDown() time.Duration {
+	return parseDurationWithDefault(r.DegradedHoldDown, "60s")
+}
+
+// GetTTL returns the TTL to answer with: the degraded TTL while fewer backends are healthy than
+// enabled, or during the hold-down period after any backend status change, and the record TTL otherwise.
+func (r *Record) GetTTL() int {
+	if r.DegradedTTL <= 0 {
+		return r.RecordTTL
+	}
+	if r.isDegraded() {
+		return r.DegradedTTL
+	}
+	holdDown := r.GetDegradedHoldDown()
+	for _, backend := range r.getBackends() {
+		if changed := backend.GetLastStatusChange(); !changed.IsZero() && time.Since(changed) < holdDown {
+			return r.DegradedTTL
+		}
+	}
+	return r.RecordTTL
+}
+
+func (r *Record) isDegraded() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.degraded
+}
+
 // GetScrapeInterval returns the health check interval for HTTPHealthCheck
 func (r *Record) GetScrapeInterval() time.Duration {
 	return parseDurationWithDefault(r.ScrapeInterval, "10s")
@@ -172,7 +399,7 @@ func (r *Record) scrapeBackends(ctx context.Context, g *GSLB) {
 	}
 
 	// for tracing only
-	for _, backend := range r.Backends {
+	for _, backend := range r.getBackends() {
 		backend.SetFqdn(r.Fqdn)
 	}
 
@@ -209,7 +436,8 @@ func (r *Record) scrapeBackends(ctx context.Context, g *GSLB) {
 			}
 
 			// Run health checks for backends
-			for _, backend := range r.Backends {
+			backends := r.getBackends()
+			for _, backend := range backends {
 				backend.Lock()
 				if !backend.IsEnabled() {
 					backend.Unlock()
@@ -221,7 +449,7 @@ func (r *Record) scrapeBackends(ctx context.Context, g *GSLB) {
 
 			// Update Prometheus gauge for active backends
 			healthyCount := 0
-			for _, backend := range r.Backends {
+			for _, backend := range backends {
 				if backend.IsHealthy() {
 					healthyCount++
 				}
@@ -230,8 +458,13 @@ func (r *Record) scrapeBackends(ctx context.Context, g *GSLB) {
 
 			// Update record health status
 			r.updateRecordHealthStatus()
+			r.mutex.Lock()
+			r.checked = true
+			r.mutex.Unlock()
 		case <-ctx.Done():
 			log.Debugf("[%s] stopping health checks", r.Fqdn)
+			// Released here so that no health check running concurrently sets the metrics back
+			releaseRecordMetrics(r.Fqdn)
 			return
 		}
 	}
@@ -245,6 +478,42 @@ func parseDurationWithDefault(durationStr string, defaultStr string) time.Durati
 	return d
 }
 
+// startHealthChecks starts the health checks of the record, until ctx is canceled or stopHealthChecks is called.
+func (r *Record) startHealthChecks(ctx context.Context, g *GSLB) {
+	trackRecordMetrics(r.Fqdn)
+	r.startTrackedHealthChecks(ctx, g)
+}
+
+// startTrackedHealthChecks starts the health checks of a record whose metrics are already tracked.
+// They are released once the health checks stop.
+func (r *Record) startTrackedHealthChecks(ctx context.Context, g *GSLB) {
+	recordCtx, cancel := context.WithCancel(ctx)
+	r.mutex.Lock()
+	r.cancelFunc = cancel
+	r.mutex.Unlock()
+	// Initialize health status for the record
+	r.updateRecordHealthStatus()
+	go r.scrapeBackends(recordCtx, g)
+}
+
+// stopHealthChecks stops the health checks of the record. Its metrics are deleted once stopped.
+func (r *Record) stopHealthChecks() {
+	r.mutex.Lock()
+	cancel := r.cancelFunc
+	r.cancelFunc = nil
+	r.mutex.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// healthChecked reports whether the record completed at least one health pass.
+func (r *Record) healthChecked() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.checked
+}
+
 func (r *Record) UpdateRecord() {
 	// Update record health status
 	r.updateRecordHealthStatus()
@@ -252,13 +521,34 @@ func (r *Record) UpdateRecord() {
 
 func (r *Record) updateRecordHealthStatus() {
 	// Check if any backend is healthy
-	hasHealthyBackend := false
-	for _, backend := range r.Backends {
+	backends := r.getBackends()
+	var healthyBackends []BackendInterface
+	enabledCount := 0
+	for _, backend := range backends {
+		// Backends in maintenance are treated as disabled
+		if backend.IsEnabled() && !backend.InMaintenance() {
+			enabledCount++
+		}
 		if backend.IsHealthy() {
-			hasHealthyBackend = true
-			break
+			healthyBackends = append(healthyBackends, backend)
 		}
 	}
+	hasHealthyBackend := len(healthyBackends) > 0
+
+	r.mutex.Lock()
+	// Remember the last known good set for the last_healthy fallback
+	if hasHealthyBackend {
+		r.lastHealthy = healthyBackends
+	}
+	r.degraded = len(healthyBackends) < enabledCount
+	changed := r.healthKnown && r.healthy != hasHealthyBackend
+	r.healthy = hasHealthyBackend
+	r.healthKnown = true
+	r.mutex.Unlock()
+	if changed {
+		log.Infof("[%s] record status change: healthy changed from %v to %v", r.Fqdn, !hasHealthyBackend, hasHealthyBackend)
+		events.Publish(Event{Type: EventRecordStatus, Record: r.Fqdn, Status: healthStatus(hasHealthyBackend), Previous: healthStatus(!hasHealthyBackend)})
+	}
 
 	// Set health status: 1 if any backend is healthy, 0 otherwise
 	if hasHealthyBackend {
@@ -268,9 +558,9 @@ func (r *Record) updateRecordHealthStatus() {
 	}
 
 	// Update individual backend health status
-	for _, backend := range r.Backends {
+	for _, backend := range backends {
 		switch {
-		case !backend.IsEnabled():
+		case !backend.IsEnabled() || backend.InMaintenance():
 			SetBackendHealthStatus(r.Fqdn, backend.GetAddress(), 2)
 		case backend.IsHealthy():
 			SetBackendHealthStatus(r.Fqdn, backend.GetAddress(), 1)
@@ -278,13 +568,20 @@ func (r *Record) updateRecordHealthStatus() {
 			SetBackendHealthStatus(r.Fqdn, backend.GetAddress(), 0)
 		}
 
-		// Update healthcheck status for each type
+		// Update healthcheck status for each type, and the aggregated decision of the policy
+		results := healthCheckTypeResults(backend.GetHealthCheckResults())
+		healthcheckTypes := []string{HealthCheckAggregateType}
 		for _, healthcheck := range backend.GetHealthChecks() {
-			healthcheckType := healthcheck.GetType()
+			healthcheckTypes = append(healthcheckTypes, healthcheck.GetType())
+		}
+		results[HealthCheckAggregateType] = backend.GetHealthCheckPassed()
+		for _, healthcheckType := range healthcheckTypes {
 			switch {
 			case !backend.IsEnabled():
 				SetBackendHealthcheckStatus(r.Fqdn, backend.GetAddress(), healthcheckType, 2)
-			case backend.IsHealthy():
+			case backend.InMaintenance():
+				SetBackendHealthcheckStatus(r.Fqdn, backend.GetAddress(), healthcheckType, 3)
+			case results[healthcheckType]:
 				SetBackendHealthcheckStatus(r.Fqdn, backend.GetAddress(), healthcheckType, 1)
 			default:
 				SetBackendHealthcheckStatus(r.Fqdn, backend.GetAddress(), healthcheckType, 0)
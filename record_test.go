@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
@@ -40,6 +41,117 @@ backends:
 	assert.Equal(t, "192.168.1.1", record.Backends[0].GetAddress())
 }
 
+func TestRecord_UnmarshalYAML_HTTPS(t *testing.T) {
+	yamlData := `
+https:
+  alpn: ["h2", "h3"]
+  port: 8443
+  ech: "AEX+DQBB"
+backends:
+  - address: "192.168.1.1"
+`
+
+	var record Record
+	err := yaml.Unmarshal([]byte(yamlData), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"h2", "h3"}, record.HTTPS.ALPN)
+	assert.Equal(t, 8443, record.HTTPS.Port)
+	assert.Equal(t, uint16(1), record.HTTPS.GetPriority())
+
+	var invalid Record
+	err = yaml.Unmarshal([]byte("https:\n  ech: \"not base64!\"\n"), &invalid)
+	assert.Error(t, err)
+}
+
+func TestRecord_UnmarshalYAML_Fallback(t *testing.T) {
+	var record Record
+	err := yaml.Unmarshal([]byte("backends:\n  - address: \"192.168.1.1\"\n"), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, FallbackAll, record.GetFallback())
+
+	yamlData := `
+fallback: static
+fallback_addresses: ["10.0.0.1", "2001:db8::1"]
+backends:
+  - address: "192.168.1.1"
+`
+	record = Record{}
+	err = yaml.Unmarshal([]byte(yamlData), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, FallbackStatic, record.GetFallback())
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1"}, record.FallbackAddresses)
+
+	record = Record{}
+	err = yaml.Unmarshal([]byte("fallback: servfail\n"), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, FallbackNone, record.GetFallback())
+
+	invalid := []string{
+		"fallback: static\n",
+		"fallback: static\nfallback_addresses: [\"not-an-ip\"]\n",
+		"fallback: cname\n",
+		"fallback: cname\nfallback_cname: \"10.0.0.1\"\n",
+		"fallback: random\n",
+	}
+	for _, data := range invalid {
+		record = Record{}
+		assert.Error(t, yaml.Unmarshal([]byte(data), &record), data)
+	}
+}
+
+func TestRecord_UpdateRecordHealthStatus_LastHealthy(t *testing.T) {
+	healthy := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true}}
+	unhealthy := &MockBackend{Backend: &Backend{Address: "192.168.1.2", Enable: true}}
+	healthy.On("IsHealthy").Return(true)
+	unhealthy.On("IsHealthy").Return(false)
+
+	record := &Record{Fqdn: testFqdn, Backends: []BackendInterface{healthy, unhealthy}}
+	record.updateRecordHealthStatus()
+	assert.Equal(t, []BackendInterface{healthy}, record.getLastHealthy())
+
+	// An outage must not erase the last known good set
+	record.Backends = []BackendInterface{unhealthy}
+	record.updateRecordHealthStatus()
+	assert.Equal(t, []BackendInterface{healthy}, record.getLastHealthy())
+}
+
+func TestRecord_UpdateRecordHealthStatus_HealthcheckStatus(t *testing.T) {
+	now := time.Now()
+	// The aggregate is the decision of the policy, which can pass while a check fails
+	passing := &Backend{
+		Address:            "192.168.1.1",
+		Enable:             true,
+		Alive:              true,
+		HealthCheckPolicy:  "any",
+		HealthCheckPassed:  true,
+		HealthChecks:       []GenericHealthCheck{&TCPHealthCheck{Port: 443}},
+		HealthCheckResults: []HealthCheckResult{{Type: "tcp/443", Error: "connection"}},
+	}
+	maintenance := &Backend{
+		Address:            "192.168.1.2",
+		Enable:             true,
+		Alive:              true,
+		HealthCheckPassed:  true,
+		HealthChecks:       []GenericHealthCheck{&TCPHealthCheck{Port: 443}},
+		HealthCheckResults: []HealthCheckResult{{Type: "tcp/443", Passed: true}},
+		Maintenance:        []MaintenanceWindow{{Start: now.Add(-time.Minute), End: now.Add(time.Hour)}},
+	}
+	disabled := &Backend{Address: "192.168.1.3", HealthChecks: []GenericHealthCheck{&TCPHealthCheck{Port: 443}}}
+
+	record := &Record{Fqdn: "status.example.com.", Backends: []BackendInterface{passing, maintenance, disabled}}
+	record.updateRecordHealthStatus()
+
+	status := func(address, typ string) float64 {
+		return testutil.ToFloat64(backendHealthcheckStatus.WithLabelValues(record.Fqdn, address, typ))
+	}
+	assert.Equal(t, 1.0, status("192.168.1.1", HealthCheckAggregateType))
+	assert.Equal(t, 0.0, status("192.168.1.1", "tcp/443"))
+	assert.Equal(t, 3.0, status("192.168.1.2", HealthCheckAggregateType))
+	assert.Equal(t, 3.0, status("192.168.1.2", "tcp/443"))
+	assert.Equal(t, 2.0, status("192.168.1.3", HealthCheckAggregateType))
+	assert.Equal(t, 2.0, status("192.168.1.3", "tcp/443"))
+}
+
 func TestRecord_UpdateRecord(t *testing.T) {
 	record := &Record{
 		Fqdn:  "example.com",
@@ -58,6 +170,86 @@ func TestRecord_UpdateRecord(t *testing.T) {
 	assert.Equal(t, "round-robin", record.Mode)
 }
 
+func TestRecord_UnmarshalYAML_DegradedTTL(t *testing.T) {
+	var record Record
+	err := yaml.Unmarshal([]byte("record_ttl: 300\ndegraded_ttl: 10\n"), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, record.DegradedTTL)
+	assert.Equal(t, 60*time.Second, record.GetDegradedHoldDown())
+
+	record = Record{}
+	assert.Error(t, yaml.Unmarshal([]byte("degraded_ttl: -1\n"), &record))
+	record = Record{}
+	assert.Error(t, yaml.Unmarshal([]byte("degraded_hold_down: soon\n"), &record))
+}
+
+func TestRecord_UnmarshalYAML_MaxAnswers(t *testing.T) {
+	var record Record
+	assert.NoError(t, yaml.Unmarshal([]byte("mode: fastest\nmax_answers: 2\n"), &record))
+	assert.Equal(t, 2, record.MaxAnswers)
+
+	record = Record{}
+	assert.Error(t, yaml.Unmarshal([]byte("max_answers: -1\n"), &record))
+}
+
+func TestRecord_UnmarshalYAML_RiseFall(t *testing.T) {
+	var record Record
+	yamlData := `
+rise: 2
+fall: 3
+backends:
+  - address: "10.0.0.1"
+  - address: "10.0.0.2"
+    fall: 1
+`
+	err := yaml.Unmarshal([]byte(yamlData), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, record.Backends[0].GetRise())
+	assert.Equal(t, 3, record.Backends[0].GetFall())
+	assert.Equal(t, 2, record.Backends[1].GetRise())
+	assert.Equal(t, 1, record.Backends[1].GetFall())
+
+	record = Record{}
+	assert.Error(t, yaml.Unmarshal([]byte("rise: 0\n"), &record))
+	record = Record{}
+	assert.Error(t, yaml.Unmarshal([]byte("backends:\n  - address: \"10.0.0.1\"\n    fall: -1\n"), &record))
+}
+
+func TestRecord_GetTTL(t *testing.T) {
+	healthy := &MockBackend{Backend: &Backend{Address: "192.168.1.1", Enable: true}}
+	unhealthy := &MockBackend{Backend: &Backend{Address: "192.168.1.2", Enable: true}}
+	healthy.On("IsHealthy").Return(true)
+	unhealthy.On("IsHealthy").Return(false)
+
+	record := &Record{Fqdn: testFqdn, RecordTTL: 300, DegradedTTL: 10, DegradedHoldDown: "1m"}
+
+	// Steady state: every enabled backend is healthy
+	record.Backends = []BackendInterface{healthy}
+	record.updateRecordHealthStatus()
+	assert.Equal(t, 300, record.GetTTL())
+
+	// Fewer healthy backends than enabled
+	record.Backends = []BackendInterface{healthy, unhealthy}
+	record.updateRecordHealthStatus()
+	assert.Equal(t, 10, record.GetTTL())
+
+	// Recovered, but a backend changed status within the hold-down period
+	record.Backends = []BackendInterface{healthy}
+	record.updateRecordHealthStatus()
+	healthy.LastStatusChange = time.Now().Add(-30 * time.Second)
+	assert.Equal(t, 10, record.GetTTL())
+
+	// Hold-down expired
+	healthy.LastStatusChange = time.Now().Add(-2 * time.Minute)
+	assert.Equal(t, 300, record.GetTTL())
+
+	// Degraded TTL disabled
+	record.DegradedTTL = 0
+	record.Backends = []BackendInterface{healthy, unhealthy}
+	record.updateRecordHealthStatus()
+	assert.Equal(t, 300, record.GetTTL())
+}
+
 func TestRecord_ScrapeInterval(t *testing.T) {
 	record := &Record{
 		ScrapeInterval: "350s",
@@ -0,0 +1,157 @@
+package gslb
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Selector is a stage of the routing policy of a record. It filters and/or orders the candidate backends
+// for the next stage, and the candidates left by the last stage are answered. Selectors are registered
+// with RegisterSelector, and named in the policy of the records or used as a mode.
+type Selector interface {
+	// Select returns the candidates kept for the next stage, in order of preference. The candidates
+	// are the healthy and enabled backends of the record matching the query type, left by the previous stage.
+	Select(s *Selection, candidates []BackendInterface) ([]BackendInterface, error)
+}
+
+// SelectorFunc adapts a function to the Selector interface.
+type SelectorFunc func(s *Selection, candidates []BackendInterface) ([]BackendInterface, error)
+
+// Select calls f(s, candidates).
+func (f SelectorFunc) Select(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+	return f(s, candidates)
+}
+
+// Selection is a query going through the routing policy of a record.
+type Selection struct {
+	GSLB       *GSLB
+	Record     *Record
+	Domain     string      // Queried name
+	RecordType uint16      // Queried type
+	Client     *ClientInfo // Client the answer is chosen for, with its ECS prefix
+	// Answers is how many of the last candidates are answered when the record does not set max_answers,
+	// 0 for all of them. It is 1 unless a stage sets it.
+	Answers int
+	// Scope is the ECS scope prefix length of the answer, 0 while it does not depend on the client.
+	Scope uint8
+	// Preview is set when the answer is not sent to the client (e.g. address hints): stages must not
+	// change any state, such as the round robin position.
+	Preview bool
+}
+
+// WidenScope records that the answer depends on the client network of the given prefix length.
+func (s *Selection) WidenScope(prefix uint8) {
+	s.Scope = max(s.Scope, prefix)
+}
+
+var selectors = struct {
+	sync.RWMutex
+	byName map[string]Selector
+}{byName: make(map[string]Selector)}
+
+// RegisterSelector registers a routing policy stage under a name. It is meant to be called from an init
+// function, before the zone files are loaded, and panics if the name is already registered.
+func RegisterSelector(name string, selector Selector) {
+	selectors.Lock()
+	defer selectors.Unlock()
+	if _, exists := selectors.byName[name]; exists {
+		panic(fmt.Sprintf("gslb: selector %s registered twice", name))
+	}
+	selectors.byName[name] = selector
+}
+
+func lookupSelector(name string) (Selector, bool) {
+	selectors.RLock()
+	defer selectors.RUnlock()
+	selector, ok := selectors.byName[name]
+	return selector, ok
+}
+
+var policies = struct {
+	sync.RWMutex
+	byName map[string][]string
+}{byName: make(map[string][]string)}
+
+// RegisterPolicy registers a routing policy under a name, usable as the mode of the records. It is meant to
+// be called from an init function, after the selectors of its stages, and panics if the name is already
+// registered or a stage is unknown.
+func RegisterPolicy(name string, stages ...string) {
+	if err := validatePolicy(stages); err != nil {
+		panic(fmt.Sprintf("gslb: policy %s: %v", name, err))
+	}
+	policies.Lock()
+	defer policies.Unlock()
+	if _, exists := policies.byName[name]; exists {
+		panic(fmt.Sprintf("gslb: policy %s registered twice", name))
+	}
+	policies.byName[name] = stages
+}
+
+// modePolicy returns the routing policy of a mode: the registered policy of that name, or else
+// the selector of that name as a policy of a single stage.
+func modePolicy(mode string) ([]string, bool) {
+	policies.RLock()
+	policy, ok := policies.byName[mode]
+	policies.RUnlock()
+	if ok {
+		return policy, true
+	}
+	if _, ok := lookupSelector(mode); ok {
+		return []string{mode}, true
+	}
+	return nil, false
+}
+
+// validatePolicy checks that every stage of a routing policy is a registered selector.
+func validatePolicy(policy []string) error {
+	for _, name := range policy {
+		if _, ok := lookupSelector(name); !ok {
+			return fmt.Errorf("unknown policy stage: %s", name)
+		}
+	}
+	return nil
+}
+
+// pickBackendWithPolicy runs the healthy backends of a record through the stages of a routing policy,
+// and answers the first candidates left by the last stage, with the ECS scope of the answer.
+func (g *GSLB) pickBackendWithPolicy(domain string, record *Record, recordType uint16, client *ClientInfo, policy []string) ([]string, uint8, error) {
+	return g.runPolicy(&Selection{GSLB: g, Record: record, Domain: domain, RecordType: recordType, Client: client}, policy)
+}
+
+// runPolicy runs a selection through the stages of a routing policy. The selected backends are counted
+// unless the selection is a preview.
+func (g *GSLB) runPolicy(s *Selection, policy []string) ([]string, uint8, error) {
+	var candidates []BackendInterface
+	for _, backend := range s.Record.Backends {
+		if backend.IsHealthy() && backend.IsEnabled() && backendMatchesType(backend, s.RecordType) {
+			candidates = append(candidates, backend)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, 0, fmt.Errorf("no healthy backends for type %d", s.RecordType)
+	}
+
+	s.Answers = 1
+	for _, name := range policy {
+		selector, ok := lookupSelector(name)
+		if !ok {
+			return nil, s.Scope, fmt.Errorf("unknown policy stage: %s", name)
+		}
+		var err error
+		if candidates, err = selector.Select(s, candidates); err != nil {
+			return nil, s.Scope, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(candidates) == 0 {
+			return nil, s.Scope, fmt.Errorf("no backend left by %s for type %d", name, s.RecordType)
+		}
+	}
+
+	addresses := []string{}
+	for _, backend := range candidates[:s.Record.answerCount(s.Answers, len(candidates))] {
+		addresses = append(addresses, backend.GetAddress())
+		if !s.Preview {
+			IncBackendSelected(s.Record.Fqdn, backend.GetAddress())
+		}
+	}
+	return addresses, s.Scope, nil
+}
@@ -0,0 +1,129 @@
+package gslb
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGSLB_PickBackendWithPolicy(t *testing.T) {
+	backends := []*Backend{
+		{Address: "10.0.0.1", Enable: true, Alive: true, Priority: 1, Location: "eu-west", Weight: 1},
+		{Address: "10.0.0.2", Enable: true, Alive: true, Priority: 2, Location: "eu-west", Weight: 1},
+		{Address: "10.0.0.3", Enable: true, Alive: true, Priority: 1, Location: "us-east", Weight: 1},
+		{Address: "10.0.0.4", Enable: true, Alive: false, Priority: 1, Location: "eu-west", Weight: 1},
+	}
+	record := &Record{
+		Fqdn:     "policy.example.com.",
+		Policy:   []string{"geoip_location", "failover"},
+		Backends: []BackendInterface{backends[0], backends[1], backends[2], backends[3]},
+	}
+	g := &GSLB{
+		Records:     map[string]map[string]*Record{"example.com.": {"policy.example.com.": record}},
+		LocationMap: map[string]string{"192.168.0.0/16": "eu-west", "192.168.1.0/24": "us-east"},
+	}
+	client := func(ip string) *ClientInfo { return &ClientInfo{IP: net.ParseIP(ip), PrefixLen: 24} }
+
+	// Each stage narrows the candidates of the previous one
+	ips, scope, err := g.pickResponse("policy.example.com.", dns.TypeA, client("192.168.2.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+	assert.Equal(t, uint8(16), scope)
+
+	// The most specific subnet decides the location
+	ips, scope, err = g.pickResponse("policy.example.com.", dns.TypeA, client("192.168.1.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3"}, ips)
+	assert.Equal(t, uint8(24), scope)
+
+	// A filter matching no backend leaves the decision to the next stage
+	ips, _, err = g.pickResponse("policy.example.com.", dns.TypeA, client("172.16.0.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, ips)
+
+	// The last stage sets the number of answers, one unless it answers all its candidates
+	record.Policy = []string{"failover", "geoip_location"}
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+	record.Policy = []string{"geoip_location"}
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+	record.MaxAnswers = 5
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, ips)
+
+	// Errors of a stage are reported with its name
+	backends[0].Capacity, backends[0].Load, backends[0].LoadReported = 10, 10, true
+	record.Policy = []string{"geoip_location", "least_loaded"}
+	record.MaxAnswers = 0
+	ips, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, ips)
+	backends[1].Capacity, backends[1].Load, backends[1].LoadReported = 10, 10, true
+	_, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.EqualError(t, err, "least_loaded: every backend is at capacity")
+
+	for _, b := range backends {
+		b.Alive = false
+	}
+	_, _, err = g.pickBackendWithPolicy(record.Fqdn, record, dns.TypeA, client("192.168.2.0"), record.Policy)
+	assert.Error(t, err)
+}
+
+func TestRegisterSelector(t *testing.T) {
+	// A selector keeping the backends tagged "canary"
+	RegisterSelector("test_canary", SelectorFunc(func(s *Selection, candidates []BackendInterface) ([]BackendInterface, error) {
+		return keepMatching(candidates, func(backend BackendInterface) bool {
+			return len(backend.GetTags()) > 0 && backend.GetTags()[0] == "canary"
+		}), nil
+	}))
+	assert.Panics(t, func() {
+		RegisterSelector("test_canary", SelectorFunc(selectFailover))
+	})
+
+	canary := &Backend{Address: "10.0.0.2", Enable: true, Alive: true, Priority: 2, Tags: []string{"canary"}}
+	record := &Record{
+		Fqdn:     "canary.example.com.",
+		Mode:     "test_canary",
+		Backends: []BackendInterface{&Backend{Address: "10.0.0.1", Enable: true, Alive: true, Priority: 1}, canary},
+	}
+	g := &GSLB{Records: map[string]map[string]*Record{"example.com.": {"canary.example.com.": record}}}
+
+	// Usable as a mode and in a policy
+	ips, _, err := g.pickResponse("canary.example.com.", dns.TypeA, &ClientInfo{IP: net.ParseIP("192.0.2.1"), PrefixLen: 32})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, ips)
+	record.Mode = "unknown"
+	_, _, err = g.pickResponse("canary.example.com.", dns.TypeA, &ClientInfo{IP: net.ParseIP("192.0.2.1"), PrefixLen: 32})
+	assert.EqualError(t, err, "unsupported mode: unknown")
+
+	var parsed Record
+	assert.NoError(t, yaml.Unmarshal([]byte("policy: [test_canary, failover]\n"), &parsed))
+	assert.Equal(t, []string{"test_canary", "failover"}, parsed.Policy)
+	err = yaml.Unmarshal([]byte("policy: [geoip_country, closest_by_magic]\n"), &parsed)
+	assert.ErrorContains(t, err, "unknown policy stage: closest_by_magic")
+}
+
+func TestRegisterPolicy(t *testing.T) {
+	RegisterPolicy("test_eu_first", "geoip_location", "failover")
+	assert.Panics(t, func() { RegisterPolicy("test_eu_first", "failover") })
+	assert.Panics(t, func() { RegisterPolicy("test_unknown_stage", "closest_by_magic") })
+
+	policy, ok := modePolicy("test_eu_first")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"geoip_location", "failover"}, policy)
+	policy, ok = modePolicy("closest")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"nearest"}, policy)
+	policy, ok = modePolicy("weighted")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"weighted"}, policy)
+	_, ok = modePolicy("unknown")
+	assert.False(t, ok)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 	"gopkg.in/fsnotify.v1"
 	"gopkg.in/yaml.v3"
 )
@@ -42,6 +43,12 @@ func setup(c *caddy.Controller) error {
 		APIEnable:                 true,
 		APIListenAddr:             "0.0.0.0",
 		APIListenPort:             "8080",
+		APIReadTimeout:            "10s",
+		APIWriteTimeout:           "30s",
+		APIHTTP2:                  true,
+		NegativeTTL:               60,
+		StateInterval:             "30s",
+		StateMaxAge:               "10m",
 	}
 
 	zoneFiles := make(map[string]string)
@@ -67,12 +74,6 @@ func setup(c *caddy.Controller) error {
 					zoneFiles[zoneNorm] = file
 
 					g.Zones[zoneNorm] = file
-					go func(filePath string) {
-						if err := startConfigWatcher(g, filePath); err != nil {
-							log.Errorf("Config watcher failed for %s: %v", filePath, err)
-						}
-						log.Errorf("Config watcher stopped unexpectedly for %s", filePath)
-					}(file)
 				case "use_edns_csubnet":
 					if c.NextArg() {
 						return c.ArgErr()
@@ -122,23 +123,23 @@ func setup(c *caddy.Controller) error {
 						pathArg := c.Val()
 						switch typeArg {
 						case "country_db":
-							countryDB, err := geoip2.Open(pathArg)
+							countryDB, networks, err := openMaxMindDB(pathArg)
 							if err != nil {
 								return fmt.Errorf("failed to open country MaxMind DB: %w", err)
 							}
-							g.GeoIPCountryDB = countryDB
+							g.GeoIPCountryDB, g.GeoIPCountryNetworks = countryDB, networks
 						case "city_db":
-							cityDB, err := geoip2.Open(pathArg)
+							cityDB, networks, err := openMaxMindDB(pathArg)
 							if err != nil {
 								return fmt.Errorf("failed to open city MaxMind DB: %w", err)
 							}
-							g.GeoIPCityDB = cityDB
+							g.GeoIPCityDB, g.GeoIPCityNetworks = cityDB, networks
 						case "asn_db":
-							asnDB, err := geoip2.Open(pathArg)
+							asnDB, networks, err := openMaxMindDB(pathArg)
 							if err != nil {
 								return fmt.Errorf("failed to open ASN MaxMind DB: %w", err)
 							}
-							g.GeoIPASNDB = asnDB
+							g.GeoIPASNDB, g.GeoIPASNNetworks = asnDB, networks
 						default:
 							return c.Errf("unknown geoip_maxmind type: %s", typeArg)
 						}
@@ -182,6 +183,30 @@ func setup(c *caddy.Controller) error {
 						return c.ArgErr()
 					}
 					g.APIListenPort = c.Val()
+				case "api_read_timeout":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					d, err := time.ParseDuration(c.Val())
+					if err != nil || d <= 0 {
+						return fmt.Errorf("invalid value for api_read_timeout, expected duration format: %v", c.Val())
+					}
+					g.APIReadTimeout = c.Val()
+				case "api_write_timeout":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					d, err := time.ParseDuration(c.Val())
+					if err != nil || d <= 0 {
+						return fmt.Errorf("invalid value for api_write_timeout, expected duration format: %v", c.Val())
+					}
+					g.APIWriteTimeout = c.Val()
+				case "api_http2":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					val := c.Val()
+					g.APIHTTP2 = !(val == "false" || val == "0")
 				case "api_basic_user":
 					if !c.NextArg() {
 						return c.ArgErr()
@@ -192,6 +217,24 @@ func setup(c *caddy.Controller) error {
 						return c.ArgErr()
 					}
 					g.APIBasicPass = c.Val()
+				case "api_auth":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					tokens, clients, err := loadAPIAuth(c.Val())
+					if err != nil {
+						return fmt.Errorf("failed to load api_auth: %w", err)
+					}
+					g.APITokens = tokens
+					g.APIClients = clients
+				case "api_client_ca":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					if _, err := loadCertPool(c.Val()); err != nil {
+						return fmt.Errorf("failed to load api_client_ca: %w", err)
+					}
+					g.APIClientCA = c.Val()
 				case "healthcheck_profiles":
 					if !c.NextArg() {
 						return c.ArgErr()
@@ -216,6 +259,60 @@ func setup(c *caddy.Controller) error {
 						return c.ArgErr()
 					}
 					g.DisableTXT = true
+				case "authoritative":
+					g.Authoritative = true
+					g.Nameservers = c.RemainingArgs()
+				case "negative_ttl":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					ttl, err := strconv.Atoi(c.Val())
+					if err != nil || ttl < 0 {
+						return fmt.Errorf("invalid value for negative_ttl: %v", c.Val())
+					}
+					g.NegativeTTL = ttl
+				case "state_file":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					g.StateFile = c.Val()
+				case "overrides_file":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					g.OverridesFile = c.Val()
+				case "audit_file":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					g.AuditFile = c.Val()
+				case "state_interval":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					d, err := time.ParseDuration(c.Val())
+					if err != nil || d <= 0 {
+						return fmt.Errorf("invalid value for state_interval, expected duration format: %v", c.Val())
+					}
+					g.StateInterval = c.Val()
+				case "state_max_age":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					d, err := time.ParseDuration(c.Val())
+					if err != nil || d <= 0 {
+						return fmt.Errorf("invalid value for state_max_age, expected duration format: %v", c.Val())
+					}
+					g.StateMaxAge = c.Val()
+				case "notifiers":
+					if !c.NextArg() {
+						return c.ArgErr()
+					}
+					notifiers, err := loadNotifiers(c.Val())
+					if err != nil {
+						return fmt.Errorf("failed to load notifiers: %w", err)
+					}
+					g.Notifiers = notifiers
 				default:
 					return c.Errf("unknown option for gslb: %s", c.Val())
 				}
@@ -227,7 +324,11 @@ func setup(c *caddy.Controller) error {
 				go watchCustomLocationMap(g, locationMapPath)
 			}
 			if g.APIEnable {
-				go g.ServeAPI()
+				// The API server of the old instance is stopped before a reload starts the new one
+				c.OnStartup(g.startAPI)
+				c.OnRestart(g.stopAPI)
+				c.OnRestartFailed(g.startAPI)
+				c.OnFinalShutdown(g.stopAPI)
 			}
 		}
 	}
@@ -238,15 +339,77 @@ func setup(c *caddy.Controller) error {
 		return g
 	})
 
+	// Send the health state changes to the notifiers, including those of the first healthchecks
+	if len(g.Notifiers) > 0 {
+		notifyCtx, cancelNotify := context.WithCancel(context.Background())
+		g.startNotifiers(notifyCtx, g.Notifiers)
+		c.OnShutdown(func() error {
+			cancelNotify()
+			return nil
+		})
+	}
+
+	// The health checks and zone file watchers of this instance are stopped when CoreDNS reloads
+	// or stops: the new instance starts its own for the zones of the new Corefile
+	recordsCtx, cancelRecords := context.WithCancel(context.Background())
+	for _, file := range zoneFiles {
+		go func(filePath string) {
+			if err := startConfigWatcher(recordsCtx, g, filePath); err != nil {
+				log.Errorf("Config watcher failed for %s: %v", filePath, err)
+			}
+		}(file)
+	}
+
 	// Initialize and load all records
-	g.initializeRecordsFromFiles(context.Background(), zoneFiles)
+	g.initializeRecordsFromFiles(recordsCtx, zoneFiles)
+	c.OnShutdown(func() error {
+		cancelRecords()
+		g.stopHealthChecks()
+		return nil
+	})
+
+	// Persist the backends health state periodically and on shutdown
+	if g.StateFile != "" {
+		stateCtx, cancel := context.WithCancel(context.Background())
+		go g.persistState(stateCtx)
+		c.OnShutdown(func() error {
+			cancel()
+			return g.saveState()
+		})
+	}
+
+	// Clear the expired backend overrides
+	sweepCtx, cancelSweep := context.WithCancel(context.Background())
+	go g.sweepOverrides(sweepCtx)
+	c.OnShutdown(func() error {
+		cancelSweep()
+		return nil
+	})
 
 	// All OK, return a nil error.
 	return nil
 }
 
-// StartConfigWatcher starts watching the configuration file for changes
-func startConfigWatcher(g *GSLB, filePath string) error {
+// openMaxMindDB reads a MaxMind DB once, for the GeoIP lookups and for the networks announced as ECS scope.
+// geoip2.Reader does not expose its maxminddb.Reader, so both readers are built on the same buffer.
+func openMaxMindDB(path string) (*geoip2.Reader, *maxminddb.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := geoip2.FromBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	networks, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, networks, nil
+}
+
+// StartConfigWatcher starts watching the configuration file for changes, until ctx is canceled
+func startConfigWatcher(ctx context.Context, g *GSLB, filePath string) error {
 	log.Debugf("Starting config watcher for %s", filePath)
 
 	// Get the directory to watch instead of the file directly
@@ -286,6 +449,10 @@ func startConfigWatcher(g *GSLB, filePath string) error {
 
 				// Set a new timer to reload the configuration after 500ms
 				reloadTimer = time.AfterFunc(500*time.Millisecond, func() {
+					if g.ownZoneWrite(filePath) {
+						log.Debugf("Zone file %s written by the records API, change already applied", filePath)
+						return
+					}
 					// Reload the configuration
 					log.Infof("Configuration file modified: %s", filePath)
 					zone := findZoneByFile(g, filePath)
@@ -304,6 +471,12 @@ func startConfigWatcher(g *GSLB, filePath string) error {
 			if err != nil {
 				log.Errorf("Error in file watcher: %v", err)
 			}
+		case <-ctx.Done():
+			if reloadTimer != nil {
+				reloadTimer.Stop()
+			}
+			log.Debugf("Stopping config watcher for %s", filePath)
+			return nil
 		}
 	}
 }
@@ -324,12 +497,19 @@ func reloadConfig(g *GSLB, filePath string, zone string) error {
 	newGSLB := &GSLB{}
 	if err := loadConfigFile(newGSLB, filePath, zone); err != nil {
 		IncConfigReloads("failure")
+		g.setZoneError(zone, err)
+		events.Publish(Event{Type: EventConfigReload, Zone: zone, Status: "failure", Reason: err.Error()})
+		g.audit(AuditEntry{Action: AuditConfigReload, Principal: "system", Source: filePath, Zone: zone, Result: "failure", Error: err.Error()})
 		return err
 	}
 
 	// Update GSLB
 	g.updateRecords(context.Background(), newGSLB)
+	g.setZoneSerial(zone)
+	g.setZoneError(zone, nil)
 	IncConfigReloads("success")
+	events.Publish(Event{Type: EventConfigReload, Zone: zone, Status: "success"})
+	g.audit(AuditEntry{Action: AuditConfigReload, Principal: "system", Source: filePath, Zone: zone})
 	return nil
 }
 
@@ -468,8 +648,10 @@ func reloadHealthcheckProfiles(profilesPath string) error {
 func reloadHealthcheckProfilesAndZones(g *GSLB, profilesPath string) error {
 	// First reload the healthcheck profiles
 	if err := reloadHealthcheckProfiles(profilesPath); err != nil {
+		g.audit(AuditEntry{Action: AuditHealthcheckProfile, Principal: "system", Source: profilesPath, Result: "failure", Error: err.Error()})
 		return err
 	}
+	g.audit(AuditEntry{Action: AuditHealthcheckProfile, Principal: "system", Source: profilesPath})
 
 	// Then reload all zones to apply the new profiles to backends
 	log.Info("Reloading all zones to apply new healthcheck profiles...")
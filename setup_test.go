@@ -1,16 +1,21 @@
 package gslb
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/coredns/caddy"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSetupGSLB(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "gslb.state")
+
 	// Define test cases
 	tests := []struct {
 		name        string
@@ -92,6 +97,27 @@ func TestSetupGSLB(t *testing.T) {
 			}`,
 			expectError: false,
 		},
+		// Test with authoritative answers
+		{
+			name: "Authoritative with nameservers and negative TTL",
+			config: `gslb {
+				zone app-x.gslb.example.com ./tests/db.app-x.gslb.example.com.yml
+				authoritative ns1.example.com ns2.example.com
+				negative_ttl 120
+			}`,
+			expectError: false,
+		},
+		// Test with a persisted state file
+		{
+			name: "State file with interval and max age",
+			config: `gslb {
+				zone app-x.gslb.example.com ./tests/db.app-x.gslb.example.com.yml
+				state_file ` + stateFile + `
+				state_interval 10s
+				state_max_age 5m
+			}`,
+			expectError: false,
+		},
 	}
 
 	// Iterate over test cases
@@ -105,8 +131,22 @@ func TestSetupGSLB(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Expected no error, but got: %v for test: %v", err, test.name)
 			}
+
+			// Stop the health checks, watchers and state persistence started by the setup
+			assert.Empty(t, shutdownCallbacks(c))
 		})
 	}
+
+	// The state is saved on shutdown
+	_, err := os.Stat(stateFile)
+	assert.NoError(t, err)
+}
+
+// shutdownCallbacks runs the shutdown callbacks registered on the instance of a test controller.
+func shutdownCallbacks(c *caddy.Controller) []error {
+	field := reflect.ValueOf(c).Elem().FieldByName("instance")
+	instance := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface().(*caddy.Instance)
+	return instance.ShutdownCallbacks()
 }
 func TestLoadRealConfig(t *testing.T) {
 	// Test loading the appX config file with healthcheck profiles
@@ -223,10 +263,11 @@ records:
 	assert.Equal(t, "192.168.1.1", firstBackendInitial.GetAddress(), "First backend address")
 	assert.Equal(t, 1, firstBackendInitial.GetPriority(), "First backend initial priority should be 1")
 
-	// Start the watcher in a goroutine
+	// Start the watcher in a goroutine, stopped at the end of the test
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	go func() {
-		// This will run forever, but we don't care - just let it run
-		_ = startConfigWatcher(g, testConfigPath)
+		_ = startConfigWatcher(ctx, g, testConfigPath)
 	}()
 
 	// Give the watcher time to start and add the file to watch
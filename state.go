@@ -0,0 +1,149 @@
+package gslb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backendState is the health state of a backend persisted in the state file.
+type backendState struct {
+	Alive           bool          `json:"alive"`
+	LastHealthcheck time.Time     `json:"last_healthcheck"`
+	ResponseTime    time.Duration `json:"response_time"`
+}
+
+// stateFile is the content of the state file: record -> backend address -> state.
+type stateFile struct {
+	SavedAt  time.Time                          `json:"saved_at"`
+	Backends map[string]map[string]backendState `json:"backends"`
+}
+
+func (g *GSLB) GetStateInterval() time.Duration {
+	d, err := time.ParseDuration(g.StateInterval)
+	if err != nil || d <= 0 {
+		d, _ = time.ParseDuration("30s")
+	}
+	return d
+}
+
+func (g *GSLB) GetStateMaxAge() time.Duration {
+	d, err := time.ParseDuration(g.StateMaxAge)
+	if err != nil || d <= 0 {
+		d, _ = time.ParseDuration("10m")
+	}
+	return d
+}
+
+// saveState writes the health state of all backends to the state file.
+func (g *GSLB) saveState() error {
+	if g.StateFile == "" {
+		return nil
+	}
+
+	state := stateFile{
+		SavedAt:  time.Now(),
+		Backends: make(map[string]map[string]backendState),
+	}
+	g.Mutex.RLock()
+	for _, records := range g.Records {
+		for fqdn, record := range records {
+			backends := make(map[string]backendState, len(record.Backends))
+			for _, backend := range record.Backends {
+				backends[backend.GetAddress()] = backend.getState()
+			}
+			state.Backends[fqdn] = backends
+		}
+	}
+	g.Mutex.RUnlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := writeFileAtomic(g.StateFile, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	log.Debugf("Saved state of %d records to %s", len(state.Backends), g.StateFile)
+	return nil
+}
+
+// restoreState loads the state file and restores the health state of the backends still configured.
+// Backends whose last healthcheck is older than the maximum age keep their default state.
+func (g *GSLB) restoreState() error {
+	if g.StateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(g.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	maxAge := g.GetStateMaxAge()
+	restored := 0
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	for _, records := range g.Records {
+		for fqdn, record := range records {
+			backends, ok := state.Backends[fqdn]
+			if !ok {
+				continue
+			}
+			for _, backend := range record.Backends {
+				s, ok := backends[backend.GetAddress()]
+				if !ok || s.LastHealthcheck.IsZero() || time.Since(s.LastHealthcheck) > maxAge {
+					continue
+				}
+				backend.setState(s)
+				restored++
+			}
+			record.updateRecordHealthStatus()
+		}
+	}
+	log.Infof("Restored state of %d backends from %s", restored, g.StateFile)
+	return nil
+}
+
+// persistState periodically saves the state file until the context is cancelled.
+func (g *GSLB) persistState(ctx context.Context) {
+	ticker := time.NewTicker(g.GetStateInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := g.saveState(); err != nil {
+				log.Errorf("%v", err)
+			}
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it, so a crash never leaves a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
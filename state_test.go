@@ -0,0 +1,86 @@
+package gslb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGSLB_SaveRestoreState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gslb.state")
+	now := time.Now().Truncate(time.Second)
+
+	g := &GSLB{
+		StateFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{
+				&Backend{Address: "10.0.0.1", Enable: true, Alive: true, LastHealthcheck: now, ResponseTime: 20 * time.Millisecond},
+				&Backend{Address: "10.0.0.2", Enable: true, Alive: false, LastHealthcheck: now},
+			}}},
+		},
+	}
+	assert.NoError(t, g.saveState())
+
+	restoredUp := &Backend{Address: "10.0.0.1", Enable: true}
+	restoredDown := &Backend{Address: "10.0.0.2", Enable: true}
+	unknown := &Backend{Address: "10.0.0.3", Enable: true}
+	g2 := &GSLB{
+		StateFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{restoredUp, restoredDown, unknown}}},
+		},
+	}
+	assert.NoError(t, g2.restoreState())
+
+	assert.True(t, restoredUp.IsHealthy())
+	assert.True(t, restoredUp.LastHealthcheck.Equal(now))
+	assert.Equal(t, 20*time.Millisecond, restoredUp.GetResponseTime())
+	assert.False(t, restoredDown.IsHealthy())
+	assert.False(t, unknown.IsHealthy())
+
+	// The first run after a restore goes through rise/fall instead of replacing the restored state
+	restoredUp.HealthChecks = []GenericHealthCheck{&toggleHealthCheck{typ: "toggle"}}
+	restoredUp.Fall = 2
+	restoredUp.runHealthChecks(0, time.Second)
+	assert.True(t, restoredUp.IsHealthy())
+	restoredUp.runHealthChecks(0, time.Second)
+	assert.False(t, restoredUp.IsHealthy())
+}
+
+func TestGSLB_RestoreState_MaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gslb.state")
+
+	g := &GSLB{
+		StateFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{&Backend{Address: "10.0.0.1", Enable: true, Alive: true, LastHealthcheck: time.Now().Add(-time.Hour)}}}},
+		},
+	}
+	assert.NoError(t, g.saveState())
+
+	stale := &Backend{Address: "10.0.0.1", Enable: true}
+	g2 := &GSLB{
+		StateFile: path,
+		Records: map[string]map[string]*Record{
+			"example.com.": {"app.example.com.": {Fqdn: "app.example.com.", Backends: []BackendInterface{stale}}},
+		},
+	}
+	g2.StateMaxAge = "10m"
+	assert.NoError(t, g2.restoreState())
+	assert.False(t, stale.IsHealthy(), "Stale state must not be restored")
+}
+
+func TestGSLB_RestoreState_MissingOrInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &GSLB{StateFile: filepath.Join(dir, "missing.state")}
+	assert.NoError(t, g.restoreState())
+
+	path := filepath.Join(dir, "invalid.state")
+	assert.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+	g = &GSLB{StateFile: path}
+	assert.Error(t, g.restoreState())
+}